@@ -1,12 +1,18 @@
 package main
 
 import (
+	"reflect"
+	"strconv"
+
 	"github.com/VKCOM/noverify/src/linter"
 	"github.com/VKCOM/noverify/src/meta"
+	"github.com/VKCOM/noverify/src/solver"
 	"github.com/VKCOM/noverify/src/state"
 	"github.com/quasilyte/php-critic/internal/constant"
+	"github.com/quasilyte/php-critic/internal/interval"
 	"github.com/z7zmey/php-parser/node"
 	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/assign"
 	"github.com/z7zmey/php-parser/node/expr/binary"
 	"github.com/z7zmey/php-parser/node/name"
 	"github.com/z7zmey/php-parser/node/scalar"
@@ -17,6 +23,34 @@ import (
 type blockChecker struct {
 	ctxt *linter.BlockContext
 	mi   *metainfoExt
+
+	// rangeChains holds "&&" and "||" nodes that were already
+	// checked as a part of the enclosing chain.
+	rangeChains map[node.Node]bool
+}
+
+// fileInfo returns the current file info or nil.
+func (c *blockChecker) fileInfo() *fileInfo {
+	info, _ := c.ctxt.RootState()[fileInfoKey].(*fileInfo)
+	return info
+}
+
+// settings returns the config settings for the current file.
+func (c *blockChecker) settings() fileSettings {
+	if info := c.fileInfo(); info != nil {
+		return info.settings
+	}
+	return nil
+}
+
+func (c *blockChecker) report(n node.Node, checkName, format string, args ...interface{}) {
+	report(c.ctxt, c.fileInfo(), n, checkName, format, args...)
+}
+
+// reportFix reports n with a fix that replaces the old node.
+// Nil fix means that the report is not fixable.
+func (c *blockChecker) reportFix(n, old, fix node.Node, checkName, format string, args ...interface{}) {
+	reportFix(c.ctxt, c.fileInfo(), n, old, fix, checkName, format, args...)
 }
 
 func (c *blockChecker) AfterEnterNode(w walker.Walkable)  {}
@@ -69,12 +103,28 @@ func (c *blockChecker) BeforeEnterNode(w walker.Walkable) {
 		c.handleBooleanAnd(n)
 	case *binary.BooleanOr:
 		c.handleBooleanOr(n)
+	case *expr.Ternary:
+		c.handleTernary(n)
+	case *binary.Coalesce:
+		c.handleCoalesce(n)
 	case *stmt.If:
 		c.handleIf(n)
+	case *stmt.While:
+		c.checkCondExpr(n.Cond)
 	case *stmt.Do:
 		c.handleDoWhile(n)
+	case *assign.Assign:
+		c.handleAssign(n)
+	case *assign.Plus:
+		c.checkNoopAssign(n, n.Variable, n.Expression, "+=", 0)
+	case *assign.Mul:
+		c.checkNoopAssign(n, n.Variable, n.Expression, "*=", 1)
+	case *assign.Concat:
+		c.handleConcatAssign(n)
 	case *stmt.Switch:
-		c.handleSwitch(n)
+		c.handleSwitch(n.Cases)
+	case *stmt.AltSwitch:
+		c.handleSwitch(n.Cases)
 	}
 }
 
@@ -83,8 +133,9 @@ func (c *blockChecker) handleGreater(cmp *binary.Greater) {
 	if ok && cv == 0 {
 		strcmp, ok := cmp.Left.(*expr.FunctionCall)
 		if ok && meta.NameNodeToString(strcmp.Function) == "strcmp" {
-			c.ctxt.Report(cmp, linter.LevelDoNotReject, "simplify",
-				"can replace 'strcmp(s1, s2) > 0' with 's1 > s2'")
+			c.reportStrcmp(cmp, strcmp, ">", func(s1, s2 node.Node) node.Node {
+				return &binary.Greater{Left: s1, Right: s2}
+			})
 		}
 	}
 }
@@ -94,8 +145,9 @@ func (c *blockChecker) handleSmaller(cmp *binary.Smaller) {
 	if ok && cv == 0 {
 		strcmp, ok := cmp.Left.(*expr.FunctionCall)
 		if ok && meta.NameNodeToString(strcmp.Function) == "strcmp" {
-			c.ctxt.Report(cmp, linter.LevelDoNotReject, "simplify",
-				"can replace 'strcmp(s1, s2) < 0' with 's1 < s2'")
+			c.reportStrcmp(cmp, strcmp, "<", func(s1, s2 node.Node) node.Node {
+				return &binary.Smaller{Left: s1, Right: s2}
+			})
 		}
 	}
 }
@@ -106,26 +158,115 @@ func (c *blockChecker) handleIdentical(eq *binary.Identical) {
 		// Handle `strcmp($s1, $s2) === 0`.
 		strcmp, ok := eq.Left.(*expr.FunctionCall)
 		if ok && meta.NameNodeToString(strcmp.Function) == "strcmp" {
-			c.ctxt.Report(eq, linter.LevelDoNotReject, "simplify",
-				"can replace 'strcmp(s1, s2) === 0' with 's1 === s2'")
+			c.reportStrcmp(eq, strcmp, "===", func(s1, s2 node.Node) node.Node {
+				return &binary.Identical{Left: s1, Right: s2}
+			})
 		}
 	}
 }
 
+// reportStrcmp reports the n comparison of the strcmp result with 0.
+// The suggestion is the direct s1 and s2 comparison built by the cmp.
+// If the strcmp arguments can't be used as the operands, only
+// the comparison operator is suggested.
+//
+// Only "===" suggestion is attached as a fix: "<" and ">" compare
+// numeric strings as numbers, so they can change the result.
+func (c *blockChecker) reportStrcmp(n node.Node, strcmp *expr.FunctionCall, op string, cmp func(s1, s2 node.Node) node.Node) {
+	var suggestion, s1, s2 node.Node
+	if len(strcmp.Arguments) == 2 {
+		var ok1, ok2 bool
+		s1, ok1 = plainArg(strcmp.Arguments[0])
+		s2, ok2 = plainArg(strcmp.Arguments[1])
+		if ok1 && ok2 {
+			suggestion = cmp(s1, s2)
+		}
+	}
+	if suggestion == nil {
+		c.report(n, "simplify",
+			"can replace '%s' with a direct '%s' comparison", codePrinter.Print(n), op)
+		return
+	}
+	// strcmp() converts its arguments to strings, but '===' compares
+	// the types too, so it's a safe replacement only for strings.
+	var fix node.Node
+	if op == "===" && c.exprType(s1).IsString() && c.exprType(s2).IsString() {
+		fix = suggestion
+	}
+	c.reportFix(n, n, fix, "simplify",
+		"can replace '%s' with '%s'", codePrinter.Print(n), codePrinter.Print(suggestion))
+}
+
 func (c *blockChecker) checkBadCond(cond node.Node) bool {
 	cv, ok := constFold(c.mi, cond).(constant.BoolValue)
 	if !ok {
 		return false
 	}
 	if cv {
-		c.ctxt.Report(cond, linter.LevelWarning, "badCond", "always true condition")
+		c.report(cond, "badCond", "always true condition")
 	} else {
-		c.ctxt.Report(cond, linter.LevelWarning, "badCond", "always false condition")
+		c.report(cond, "badCond", "always false condition")
 	}
 	return true
 }
 
+func (c *blockChecker) handleTernary(e *expr.Ternary) {
+	cv, ok := constant.ToBool(constFold(c.mi, e.Condition))
+	if !ok {
+		return
+	}
+	switch {
+	case bool(cv):
+		c.report(e.IfFalse, "deadBranch",
+			"unreachable ternary branch: condition is always true")
+	case e.IfTrue != nil:
+		c.report(e.IfTrue, "deadBranch",
+			"unreachable ternary branch: condition is always false")
+	}
+}
+
+func (c *blockChecker) handleCoalesce(e *binary.Coalesce) {
+	isset := constFoldIsset(c.mi, e.Left)
+	if isset == constant.BoolValue(false) {
+		return
+	}
+	if isset != constant.BoolValue(true) {
+		// Scalars and other non-null constants.
+		switch constFold(c.mi, e.Left).(type) {
+		case constant.UnknownValue, constant.NullValue:
+			return
+		}
+	}
+	c.report(e.Right, "deadBranch",
+		"unreachable ?? operand: left operand is never null")
+}
+
+// checkCondExpr runs checkBadCond for condition expressions
+// that are not checked by their own node handlers.
+//
+// Bare constants like in "while (true)" are permitted.
+func (c *blockChecker) checkCondExpr(cond node.Node) {
+	switch cond := cond.(type) {
+	case *expr.FunctionCall, *expr.Isset:
+		c.checkBadCond(cond)
+	case *expr.BooleanNot:
+		c.checkCondExpr(cond.Expr)
+	}
+}
+
 func (c *blockChecker) handleIf(ifstmt *stmt.If) {
+	c.checkCondExpr(ifstmt.Cond)
+	for _, elseif := range ifstmt.ElseIf {
+		c.checkCondExpr(elseif.(*stmt.ElseIf).Cond)
+	}
+
+	conds := []node.Node{ifstmt.Cond}
+	for _, elseif := range ifstmt.ElseIf {
+		conds = append(conds, elseif.(*stmt.ElseIf).Cond)
+	}
+	dups := c.checkDupConds(conds)
+	c.checkElseIfRanges(ifstmt, dups)
+
 	bodies := make([]node.Node, 0, 2+len(ifstmt.ElseIf))
 	bodies = append(bodies, ifstmt.Stmt)
 	for _, elseif := range ifstmt.ElseIf {
@@ -138,140 +279,490 @@ func (c *blockChecker) handleIf(ifstmt *stmt.If) {
 		for j, b2 := range bodies[i+1:] {
 			j += i + 1
 			if sameNode(b1, b2) {
-				c.ctxt.Report(ifstmt, linter.LevelWarning, "dupBranchBody",
+				c.report(ifstmt, "dupBranchBody",
 					"duplicated <%d> and <%d> bodies", i, j)
 			}
 		}
 	}
 }
 
+// checkDupConds reports conds that are equal to the preceding ones.
+// Returns the reported duplicates.
+func (c *blockChecker) checkDupConds(conds []node.Node) map[node.Node]bool {
+	dups := map[node.Node]bool{}
+	for i, cond := range conds {
+		for _, prev := range conds[:i] {
+			if !dups[prev] && sameExpr(c.mi, prev, cond) {
+				c.report(cond, "dupCond",
+					"duplicated condition, first occurrence at line %d", nodeLine(c.mi, prev))
+				dups[cond] = true
+				break
+			}
+		}
+	}
+	return dups
+}
+
+// checkElseIfRanges reports elseif branches whose conditions
+// are already decided by the previous conditions of the if-else chain,
+// like "$x > 20" after "$x > 10".
+//
+// dups are duplicated conditions that are already reported.
+func (c *blockChecker) checkElseIfRanges(ifstmt *stmt.If, dups map[node.Node]bool) {
+	// excluded are ranges that don't hold in the current branch.
+	excluded := chainRanges(c.mi, nil, ifstmt.Cond, false)
+	remaining := func(subject node.Node) interval.Set {
+		set := interval.Full()
+		for _, r := range excluded {
+			if sameExpr(c.mi, r.subject, subject) {
+				set = set.Intersect(r.set.Complement())
+			}
+		}
+		return set
+	}
+
+	for _, elseif := range ifstmt.ElseIf {
+		cond := elseif.(*stmt.ElseIf).Cond
+		if dups[cond] {
+			continue
+		}
+		impossible := false
+		for _, r := range chainRanges(c.mi, nil, cond, true) {
+			if r.set.Intersect(remaining(r.subject)).IsEmpty() {
+				impossible = true
+				break
+			}
+		}
+		if impossible {
+			c.report(cond, "deadBranch",
+				"unreachable elseif branch: condition is excluded by previous conditions")
+			continue
+		}
+		if r, ok := condRange(c.mi, cond); ok && remaining(r.subject).SubsetOf(r.set) {
+			c.report(cond, "badCond",
+				"always true condition: implied by previous conditions")
+			return
+		}
+		excluded = chainRanges(c.mi, excluded, cond, false)
+	}
+}
+
 func (c *blockChecker) handleDoWhile(while *stmt.Do) {
+	// "do { ... } while (false)" is a common idiom for a block
+	// that can be left with a break, so a bare false constant is permitted.
+	if _, ok := while.Cond.(*expr.ConstFetch); ok && constFold(c.mi, while.Cond) == constant.BoolValue(false) {
+		return
+	}
 	c.checkBadCond(while.Cond)
 }
 
-func (c *blockChecker) handleSwitch(swt *stmt.Switch) {
-	for _, cas := range swt.Cases {
+func (c *blockChecker) handleSwitch(cases []node.Node) {
+	for _, cas := range cases {
 		cas, ok := cas.(*stmt.Case)
 		if !ok {
 			continue // Could be *stmt.Default
 		}
 		c.checkBadCond(cas.Cond)
 	}
+	c.checkSwitchLabels(cases)
+	c.checkSwitchBodies(cases)
 }
 
-func (c *blockChecker) checkDupArg(call *expr.FunctionCall, i, j int) {
-	if len(call.Arguments) <= intMax(i, j) {
-		return
+// checkSwitchLabels reports case labels that can never be matched
+// because one of the preceding labels matches the same values.
+//
+// Switch uses a loose comparison, so "case 1" and "case '1'" are duplicates.
+// "case true" shadows the following labels that match only truthy values,
+// while "case false" and "case null" shadow the empty string label.
+func (c *blockChecker) checkSwitchLabels(cases []node.Node) {
+	type label struct {
+		cond node.Node
+		val  constant.Value
 	}
-	x := call.Arguments[i]
-	y := call.Arguments[j]
-	if sameSimpleExpr(x, y) {
-		c.ctxt.Report(x, linter.LevelWarning, "dupArg", "suspiciously duplicated argument")
+
+	var labels []label
+	for _, cas := range cases {
+		cas, ok := cas.(*stmt.Case)
+		if !ok {
+			continue
+		}
+		cur := label{cond: cas.Cond, val: constFold(c.mi, cas.Cond)}
+		for _, prev := range labels {
+			if sameExpr(c.mi, prev.cond, cur.cond) {
+				c.report(cas, "dupCase",
+					"duplicated case label, first occurrence at line %d", nodeLine(c.mi, prev.cond))
+				break
+			}
+			if !labelCovers(prev.val, cur.val) {
+				continue
+			}
+			if isCatchAllLabel(prev.val) && constant.Identical(prev.val, cur.val) != constant.BoolValue(true) {
+				c.report(cas, "deadBranch",
+					"unreachable case: shadowed by the case at line %d", nodeLine(c.mi, prev.cond))
+			} else {
+				c.report(cas, "dupCase",
+					"duplicated case label, first occurrence at line %d", nodeLine(c.mi, prev.cond))
+			}
+			break
+		}
+		labels = append(labels, cur)
 	}
 }
 
-func (c *blockChecker) handleDupSubExpr(n node.Node, lhs, rhs node.Node, op string) {
-	if sameSimpleExpr(lhs, rhs) {
-		c.ctxt.Report(n, linter.LevelWarning, "dupSubExpr", "suspiciously duplicated LHS and RHS of '%s'", op)
+// labelCovers reports whether every switch subject that matches
+// the cur case label also matches the prev label.
+//
+// Loose comparison is not transitive, so labels are compared only
+// with the labels of the same kind. For example, "case 'abc'" doesn't
+// cover "case 0": PHP 7 "0" subject doesn't match 'abc', but matches 0.
+func labelCovers(prev, cur constant.Value) bool {
+	switch prev := prev.(type) {
+	case constant.BoolValue:
+		if prev {
+			return isTruthyLabel(cur)
+		}
+		return cur == constant.BoolValue(false) || cur == (constant.NullValue{}) || isEmptyLabel(cur)
+	case constant.NullValue:
+		return cur == (constant.NullValue{}) || isEmptyLabel(cur)
+	}
+
+	x, prevNumeric := labelNumber(prev)
+	y, curNumeric := labelNumber(cur)
+	switch {
+	case prevNumeric && curNumeric:
+		// PHP 7 matches "1abc" subject with 1, but not with '1',
+		// so numeric string doesn't cover a number.
+		_, prevString := prev.(constant.StringValue)
+		_, curString := cur.(constant.StringValue)
+		if prevString && !curString && constant.TargetVersion < constant.PHP8 {
+			return false
+		}
+		return constant.Equal(x, y) == constant.BoolValue(true)
+	case prevNumeric || curNumeric:
+		return false
 	}
+	return constant.Identical(prev, cur) == constant.BoolValue(true)
 }
 
-func (c *blockChecker) handleBooleanOr(cond *binary.BooleanOr) {
-	if !c.checkBadCond(cond) {
-		c.handleBooleanOrNeqNeq(cond)
+// labelNumber returns the numeric value of the number or numeric string label.
+func labelNumber(v constant.Value) (constant.Value, bool) {
+	switch v := v.(type) {
+	case constant.IntValue, constant.FloatValue:
+		return v, true
+	case constant.StringValue:
+		return constant.NumericString(v)
+	}
+	return nil, false
+}
+
+// isTruthyLabel reports whether all values that match the case label are truthy.
+func isTruthyLabel(v constant.Value) bool {
+	if v == constant.BoolValue(true) {
+		return true
+	}
+	// Numbers and numeric strings are matched only by the equal numbers,
+	// numeric strings and true, but non-numeric strings can match 0 in PHP 7.
+	x, ok := labelNumber(v)
+	return ok && constant.Equal(x, constant.IntValue(0)) == constant.BoolValue(false)
+}
+
+// isEmptyLabel reports whether v is an empty string or an empty array label.
+// Such labels are matched only by the values that are loosely equal to null.
+func isEmptyLabel(v constant.Value) bool {
+	switch v := v.(type) {
+	case constant.StringValue:
+		return v == ""
+	case *constant.ArrayValue:
+		return constant.Equal(v, constant.NullValue{}) == constant.BoolValue(true)
+	}
+	return false
+}
+
+// isCatchAllLabel reports whether the case label v matches
+// all truthy or all falsy switch subjects.
+func isCatchAllLabel(v constant.Value) bool {
+	switch v.(type) {
+	case constant.BoolValue, constant.NullValue:
+		return true
+	}
+	return false
+}
+
+// checkSwitchBodies reports cases with identical bodies that can be merged.
+// Only bodies that don't fall through are compared.
+func (c *blockChecker) checkSwitchBodies(cases []node.Node) {
+	var bodies [][]node.Node
+	for _, cas := range cases {
+		body := caseStmts(cas)
+		if len(body) == 0 || !isCaseTerminator(body[len(body)-1]) {
+			bodies = append(bodies, nil)
+			continue
+		}
+		for i, prev := range bodies {
+			if prev != nil && sameStmts(prev, body) {
+				c.report(cas, "dupBranchBody",
+					"case body is identical to the case at line %d, consider merging them", nodeLine(c.mi, cases[i]))
+				break
+			}
+		}
+		bodies = append(bodies, body)
 	}
 }
 
-func (c *blockChecker) handleBooleanOrNeqNeq(cond *binary.BooleanOr) {
-	lhs, ok := cond.Left.(*binary.NotEqual)
-	if !ok {
-		return
+func caseStmts(cas node.Node) []node.Node {
+	switch cas := cas.(type) {
+	case *stmt.Case:
+		return cas.Stmts
+	case *stmt.Default:
+		return cas.Stmts
+	}
+	return nil
+}
+
+// isCaseTerminator reports whether s prevents a fall through to the next case.
+func isCaseTerminator(s node.Node) bool {
+	switch s.(type) {
+	case *stmt.Break, *stmt.Continue, *stmt.Return, *stmt.Throw:
+		return true
 	}
-	rhs, ok := cond.Right.(*binary.NotEqual)
-	if !ok {
-		return
+	return false
+}
+
+func sameStmts(xs, ys []node.Node) bool {
+	if len(xs) != len(ys) {
+		return false
 	}
-	if !sameSimpleExpr(lhs.Left, rhs.Left) {
-		return
+	for i := range xs {
+		if !sameNode(xs[i], ys[i]) {
+			return false
+		}
 	}
+	return true
+}
 
-	x := constFold(c.mi, lhs.Right)
-	y := constFold(c.mi, rhs.Right)
-	res, ok := constant.Equal(x, y).(constant.BoolValue)
-	if ok && !bool(res) {
-		c.ctxt.Report(cond, linter.LevelWarning, "badCond", "always true condition")
+func (c *blockChecker) handleAssign(a *assign.Assign) {
+	if sameExpr(c.mi, a.Variable, a.Expression) {
+		c.report(a, "selfAssign", "suspicious self-assignment")
 	}
 }
 
-func (c *blockChecker) handleBooleanAndLtGt(cond *binary.BooleanAnd) {
-	lhs, ok := cond.Left.(*binary.Smaller)
-	if !ok {
+func (c *blockChecker) handleConcatAssign(a *assign.Concat) {
+	// Concatenation converts a non-string lhs to string.
+	if !c.exprType(a.Variable).IsString() {
 		return
 	}
-	rhs, ok := cond.Right.(*binary.Greater)
-	if !ok {
-		return
+	s, ok := constFold(c.mi, a.Expression).(constant.StringValue)
+	if ok && s == "" {
+		c.report(a, "noop", "'.=' with an empty string has no effect")
 	}
-	if !sameSimpleExpr(lhs.Left, rhs.Left) {
+}
+
+// checkNoopAssign reports compound assignments like "$x += 0"
+// whose rhs is an identity element of the op.
+//
+// Arithmetic converts the lhs to a number, and a float rhs makes
+// an int lhs float, so lhs of other types is not reported.
+func (c *blockChecker) checkNoopAssign(a, lhs, rhs node.Node, op string, identity int) {
+	var isIdentity, isFloat bool
+	switch v := constFold(c.mi, rhs).(type) {
+	case constant.IntValue:
+		isIdentity = v == constant.IntValue(identity)
+	case constant.FloatValue:
+		isIdentity = v == constant.FloatValue(identity)
+		isFloat = true
+	}
+	if !isIdentity {
 		return
 	}
-
-	x := constFold(c.mi, lhs.Right)
-	y := constFold(c.mi, rhs.Right)
-	res, ok := constant.LessThan(x, y).(constant.BoolValue)
-	if ok && bool(res) {
-		c.ctxt.Report(cond, linter.LevelWarning, "badCond", "always false condition")
+	typ := c.exprType(lhs)
+	isNumber := !typ.IsEmpty()
+	typ.Iterate(func(t string) {
+		switch t {
+		case "float", "double":
+		case "int", "integer":
+			isNumber = isNumber && !isFloat
+		default:
+			isNumber = false
+		}
+	})
+	if isNumber {
+		c.report(a, "noop", "'%s %d' has no effect", op, identity)
 	}
 }
 
-func (c *blockChecker) handleBooleanAndEqEq(cond *binary.BooleanAnd) {
-	lhs, ok := cond.Left.(*binary.Equal)
-	if !ok {
+// exprType returns the n expression type inferred by the linter.
+func (c *blockChecker) exprType(n node.Node) *meta.TypesMap {
+	return solver.ExprType(c.ctxt.Scope(), c.ctxt.ClassParseState(), n)
+}
+
+func (c *blockChecker) checkDupArg(call *expr.FunctionCall, i, j int) {
+	if len(call.Arguments) <= intMax(i, j) {
 		return
 	}
-	rhs, ok := cond.Right.(*binary.Equal)
-	if !ok {
-		return
+	x := call.Arguments[i]
+	y := call.Arguments[j]
+	if sameExpr(c.mi, x, y) {
+		c.report(x, "dupArg", "suspiciously duplicated argument")
 	}
-	if !sameSimpleExpr(lhs.Left, rhs.Left) {
-		return
+}
+
+func (c *blockChecker) handleDupSubExpr(n node.Node, lhs, rhs node.Node, op string) {
+	if sameExpr(c.mi, lhs, rhs) {
+		c.report(n, "dupSubExpr", "suspiciously duplicated LHS and RHS of '%s': '%s'", op, codePrinter.Print(lhs))
 	}
+}
 
-	x := constFold(c.mi, lhs.Right)
-	y := constFold(c.mi, rhs.Right)
-	res, ok := constant.Equal(x, y).(constant.BoolValue)
-	if ok && !bool(res) {
-		c.ctxt.Report(cond, linter.LevelWarning, "badCond", "always false condition")
+func (c *blockChecker) handleBooleanOr(cond *binary.BooleanOr) {
+	if !c.checkBadCond(cond) {
+		c.checkCondChain(cond)
 	}
 }
 
 func (c *blockChecker) handleBooleanAnd(cond *binary.BooleanAnd) {
 	if !c.checkBadCond(cond) {
-		c.handleBooleanAndLtGt(cond)
-		c.handleBooleanAndEqEq(cond)
+		c.checkCondChain(cond)
 	}
 }
 
+// checkCondChain finds duplicated operands inside the "&&" or "||" chain.
+// It also finds contradictions, tautologies and redundant operands
+// among the numeric comparisons of the chain.
+//
+// Chain operands are grouped by their subject expressions,
+// so "$x > 0 && $y && $x < 0" is still reported.
+func (c *blockChecker) checkCondChain(cond node.Node) {
+	if c.rangeChains[cond] {
+		return // Already checked as a part of the enclosing chain
+	}
+	_, isAnd := cond.(*binary.BooleanAnd)
+	operands := c.flattenChain(cond, nil)
+	dups := c.checkDupConds(operands)
+
+	type rangeGroup struct {
+		subject  node.Node
+		operands []node.Node
+		sets     []interval.Set
+	}
+	var groups []*rangeGroup
+	for _, operand := range operands {
+		if dups[operand] {
+			continue
+		}
+		r, ok := condRange(c.mi, operand)
+		if !ok {
+			continue
+		}
+		var g *rangeGroup
+		for _, g2 := range groups {
+			if sameExpr(c.mi, g2.subject, r.subject) {
+				g = g2
+				break
+			}
+		}
+		if g == nil {
+			g = &rangeGroup{subject: r.subject}
+			groups = append(groups, g)
+		}
+		g.operands = append(g.operands, operand)
+		g.sets = append(g.sets, r.set)
+	}
+
+	combine := func(sets []interval.Set, skip func(i int) bool) interval.Set {
+		res := interval.Full()
+		if !isAnd {
+			res = interval.Empty()
+		}
+		for i, set := range sets {
+			switch {
+			case skip(i):
+			case isAnd:
+				res = res.Intersect(set)
+			default:
+				res = res.Union(set)
+			}
+		}
+		return res
+	}
+
+	for _, g := range groups {
+		set := combine(g.sets, func(int) bool { return false })
+		if isAnd && set.IsEmpty() {
+			c.report(cond, "badCond", "always false condition")
+			return
+		}
+		if !isAnd && set.IsFull() {
+			c.report(cond, "badCond", "always true condition")
+			return
+		}
+	}
+
+	for _, g := range groups {
+		redundant := make([]bool, len(g.sets))
+		for i := range g.sets {
+			others := combine(g.sets, func(j int) bool { return j == i || redundant[j] })
+			if isAnd && others.SubsetOf(g.sets[i]) && len(g.sets) > 1 {
+				redundant[i] = true
+				c.report(g.operands[i], "redundantCond",
+					"redundant sub-condition: implied by other && operands")
+			}
+			if !isAnd && g.sets[i].SubsetOf(others) {
+				redundant[i] = true
+				c.report(g.operands[i], "redundantCond",
+					"redundant sub-condition: covered by other || operands")
+			}
+		}
+	}
+}
+
+// flattenChain appends the operands of the cond chain to dst.
+// Nested chain nodes are marked as checked.
+func (c *blockChecker) flattenChain(cond node.Node, dst []node.Node) []node.Node {
+	var x, y node.Node
+	switch cond := cond.(type) {
+	case *binary.BooleanAnd:
+		x, y = cond.Left, cond.Right
+	case *binary.BooleanOr:
+		x, y = cond.Left, cond.Right
+	}
+	for _, operand := range []node.Node{x, y} {
+		if reflect.TypeOf(operand) == reflect.TypeOf(cond) {
+			c.rangeChains[operand] = true
+			dst = c.flattenChain(operand, dst)
+		} else {
+			dst = append(dst, operand)
+		}
+	}
+	return dst
+}
+
 func (c *blockChecker) handleFunctionCall(call *expr.FunctionCall) {
 	name, ok := call.Function.(*name.Name)
 	if !ok {
 		return
 	}
 
-	switch meta.NameNodeToString(name) {
+	funcName := meta.NameNodeToString(name)
+	switch funcName {
 	case "define":
 		c.checkDefine(call)
 	case "strpos":
 		c.checkStrpos(call)
-		c.checkDupArg(call, 0, 1)
 	case "strncmp":
 		c.checkStrncmp(call)
-		c.checkDupArg(call, 0, 1)
-	case "strcmp", "min", "max", "str_replace":
-		c.checkDupArg(call, 0, 1)
+	}
+
+	for _, f := range c.settings().stringsParam("dupArg", "functions", defaultDupArgFuncs) {
+		if f == funcName {
+			c.checkDupArg(call, 0, 1)
+			break
+		}
 	}
 }
 
+// defaultDupArgFuncs are the functions whose first two
+// arguments are checked by the dupArg by default.
+var defaultDupArgFuncs = []string{"strcmp", "strncmp", "strpos", "min", "max", "str_replace"}
+
 func (c *blockChecker) checkStrncmp(strncmp *expr.FunctionCall) {
 	if len(strncmp.Arguments) != 3 {
 		return
@@ -294,14 +785,18 @@ func (c *blockChecker) checkStrncmp(strncmp *expr.FunctionCall) {
 		return
 	}
 	if int(length) != validLen {
-		c.ctxt.Report(strncmp.Arguments[2], linter.LevelWarning, "badCall",
+		var fix node.Node
+		if _, ok := plainArg(strncmp.Arguments[2]); ok {
+			fix = &scalar.Lnumber{Value: strconv.Itoa(validLen)}
+		}
+		c.reportFix(strncmp.Arguments[2], strncmp.Arguments[2], fix, "badCall",
 			"expected length arg to be %d, got %d", validLen, length)
 	}
 }
 
 func (c *blockChecker) checkDefine(define *expr.FunctionCall) {
 	if len(define.Arguments) != 2 {
-		c.ctxt.Report(define.Arguments[2], linter.LevelWarning, "sloppyArg", "don't use case_insensitive argument")
+		c.report(define.Arguments[2], "sloppyArg", "don't use case_insensitive argument")
 	}
 }
 
@@ -312,7 +807,7 @@ func (c *blockChecker) checkStrpos(call *expr.FunctionCall) {
 	str := call.Arguments[0].(*node.Argument).Expr
 	substr := call.Arguments[1].(*node.Argument).Expr
 	if c.isStringLit(str) && !c.isStringLit(substr) {
-		c.ctxt.Report(call, linter.LevelWarning, "argOrder", "suspicious args order")
+		c.report(call, "argOrder", "suspicious args order")
 	}
 }
 
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -57,15 +60,104 @@ func TestDupSubExpr(t *testing.T) {
 	`)
 
 	reports = filterReports(reports, "dupSubExpr")
+	matchReports(t, reports,
+		`suspiciously duplicated LHS and RHS of '==': '0'`,
+		`suspiciously duplicated LHS and RHS of '==': '$mask'`,
+		`suspiciously duplicated LHS and RHS of '===': '0'`,
+		`suspiciously duplicated LHS and RHS of '<': '$xs[$i]'`,
+		`suspiciously duplicated LHS and RHS of '>=': '$i'`,
+		`suspiciously duplicated LHS and RHS of '-': '$i'`,
+		`suspiciously duplicated LHS and RHS of '/': '$i'`,
+		`suspiciously duplicated LHS and RHS of '%': '$i'`)
+}
+
+func TestDupSubExprStructural(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function f($x) {}
+	function strcmp($s1, $s2) {}
+	`, `<?php
+	function g($a, $b, $s) {
+		$_ = ($a + $b) - ($b + $a);
+		$_ = $a * 2 == 2 * $a;
+		$_ = $a > $b || $b < $a; // Not a dupSubExpr, but a dupCond
+		$_ = ($a . $b) === ($b . $a); // OK: not commutative
+		$_ = f($a) - f($a); // OK: calls can have side effects
+		$_ = [$a, 1] == [$a, 1];
+		$_ = strcmp($s . 'x', $s . 'x');
+		if ($a) {
+			$_ = $a + 1; // A comment
+		} else {
+			$_ = $a + 1;
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`suspiciously duplicated LHS and RHS of '-': '$a + $b'`,
+		`suspiciously duplicated LHS and RHS of '==': '$a * 2'`,
+		`duplicated condition, first occurrence at line 5`,
+		`suspiciously duplicated LHS and RHS of '==': '[$a, 1]'`,
+		`suspiciously duplicated argument`,
+		`duplicated <0> and <1> bodies`)
+}
+
+func TestDupSubExprPure(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function rand() {}
+	function strlen($s) {}
+	function undefined_func($x) {}
+	`, `<?php
+	function sq($x) { return $x * $x; }
+	function twice($x) {
+		$y = sq($x);
+		$arr = [];
+		$arr[] = $y;
+		return $y + $y + $arr[0];
+	}
+	function logged($x) { echo $x; return $x; }
+	function counter() { static $n = 0; return $n++; }
+	function calls_logged($x) { return logged($x); }
+	function rec($n) { return $n ? rec($n - 1) : 0; }
+	function even($n) { return $n ? odd($n - 1) : 1; }
+	function odd($n) { return $n ? even($n - 1) : sq($n); }
+	function uses_sq($x) { return sq($x) + 1; }
+	function modifies_arg($x) { $x[0] = 1; return $x; }
+
+	class C {
+		public $a;
+		public function m() {
+			return $this->a == $this->a;
+		}
+	}
+
+	function f($x, C $c) {
+		$_ = sq($x) - sq($x);
+		$_ = twice($x) - twice($x);
+		$_ = strlen($x) - strlen($x);
+		$_ = $c->a / $c->a;
+		$_ = logged($x) - logged($x); // OK: echo
+		$_ = counter() - counter(); // OK: static
+		$_ = calls_logged($x) - calls_logged($x); // OK: impure callee
+		$_ = rand() - rand(); // OK: builtin impure func
+		$_ = undefined_func($x) - undefined_func($x); // OK: no body
+		$_ = rec($x) - rec($x); // OK: recursive
+		$_ = even($x) - even($x); // OK: mutually recursive
+		$_ = odd($x) - odd($x); // OK: mutually recursive
+		$_ = uses_sq($x) - uses_sq($x);
+		$_ = modifies_arg($x) - modifies_arg($x); // OK: arg can be an object
+		$_ = sq(rand()) - sq(rand()); // OK: impure args
+	}
+	`)
+
 	matchReports(t, reports,
 		`suspiciously duplicated LHS and RHS of '=='`,
-		`suspiciously duplicated LHS and RHS of '=='`,
-		`suspiciously duplicated LHS and RHS of '==='`,
-		`suspiciously duplicated LHS and RHS of '<'`,
-		`suspiciously duplicated LHS and RHS of '>='`,
 		`suspiciously duplicated LHS and RHS of '-'`,
-		`suspiciously duplicated LHS and RHS of '/'`,
-		`suspiciously duplicated LHS and RHS of '%'`)
+		`suspiciously duplicated LHS and RHS of '-'`,
+		`suspiciously duplicated LHS and RHS of '-'`,
+		`suspiciously duplicated LHS and RHS of '-'`,
+		`suspiciously duplicated LHS and RHS of '/'`)
 }
 
 func TestBadCondSwitch(t *testing.T) {
@@ -82,6 +174,458 @@ func TestBadCondSwitch(t *testing.T) {
 		`always true condition`)
 }
 
+func TestSwitchCases(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f($x, $y) {
+		switch ($x):
+		case 'x':
+		case 'x':
+			echo 1;
+		endswitch;
+
+		switch ($x) {
+		case 1:
+			echo 1;
+			break;
+		case "1":
+			echo 2;
+			break;
+		case 2 - 1:
+			echo 3;
+			break;
+		case 1.0:
+			echo 4;
+			break;
+		case $y:
+			echo 5;
+			break;
+		case $y:
+			echo 6;
+			break;
+		}
+
+		switch ($x) {
+		case 'a':
+			echo 1;
+			break;
+		case true:
+			echo 2;
+			break;
+		case 'b': // OK: PHP 7 matches 0 subject with 'b', but not with true
+			echo 3;
+			break;
+		case false:
+			echo 4;
+			break;
+		case '':
+			echo 5;
+			break;
+		}
+
+		switch ($x) {
+		case 0:
+			echo 1;
+			break;
+		case true: // OK: matches other truthy values
+			echo 2;
+			break;
+		}
+
+		switch ($x) {
+		case 'abc':
+			echo 1;
+			break;
+		case 0: // OK: "0" subject doesn't match 'abc'
+			echo 2;
+			break;
+		case '0':
+			echo 3;
+			break;
+		case 0.0:
+			echo 4;
+			break;
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`duplicated case label, first occurrence at line 4`,
+		`duplicated case label, first occurrence at line 10`,
+		`duplicated case label, first occurrence at line 10`,
+		`duplicated case label, first occurrence at line 10`,
+		`duplicated case label, first occurrence at line 22`,
+		`always true condition`,
+		`always false condition`,
+		`unreachable case: shadowed by the case at line 40`,
+		`always true condition`,
+		`duplicated case label, first occurrence at line 61`,
+		`duplicated case label, first occurrence at line 61`)
+}
+
+func TestSwitchDupBody(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f($x) {
+		switch ($x) {
+		case 1:
+			echo 'a';
+			break;
+		case 2:
+			echo 'b';
+			break;
+		case 3:
+			echo 'a';
+			break;
+		case 4:
+			echo 'c';
+			// fallthrough
+		case 5:
+			echo 'c';
+			return;
+		case 6:
+			echo 'c';
+			return;
+		default:
+			echo 'b';
+			break;
+		}
+
+		switch ($x) {
+		case 1:
+		case 2: // OK: empty bodies
+			echo 'x';
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`case body is identical to the case at line 4, consider merging them`,
+		`case body is identical to the case at line 16, consider merging them`,
+		`case body is identical to the case at line 7, consider merging them`)
+}
+
+func TestSelfAssign(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	class C {
+		public $a;
+		public function f($x, $arr, $i) {
+			$x = $x;
+			$this->a = $this->a;
+			$arr[$i] = $arr[$i];
+			$arr[$i + 1] = $arr[1 + $i];
+			$arr[$i] = $arr[$i + 1]; // OK: different elements
+			$this->a = $x;
+			$arr[$this->g()] = $arr[$this->g()]; // OK: calls can have side effects
+			return [$x, $arr];
+		}
+		public function g() { return 0; }
+	}
+	`)
+
+	matchReports(t, reports,
+		`suspicious self-assignment`,
+		`suspicious self-assignment`,
+		`suspicious self-assignment`,
+		`suspicious self-assignment`)
+}
+
+func TestNoopAssign(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f(int $x, float $y, string $s, $mixed) {
+		$one = 1;
+		$x += 0;
+		$x *= $one;
+		$y += 0;
+		$y *= 1.0;
+		$s .= '';
+		$s .= "" . '';
+		$x += 1; // OK
+		$x *= 0; // OK: not an identity
+		$x += 0.0; // OK: int becomes float
+		$x -= 0; // OK: not reported
+		$x |= 0; // OK: not reported
+		$mixed += 0; // OK: converts to number
+		$mixed .= ''; // OK: converts to string
+		$s += 0; // OK: converts to number
+		$s .= '0'; // OK
+		$s .= 0; // OK: appends "0"
+		return [$x, $y, $s, $mixed];
+	}
+	`)
+
+	matchReports(t, reports,
+		`'+= 0' has no effect`,
+		`'*= 1' has no effect`,
+		`'+= 0' has no effect`,
+		`'*= 1' has no effect`,
+		`'.=' with an empty string has no effect`,
+		`'.=' with an empty string has no effect`)
+}
+
+func TestDupCode(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	function sum_positive($xs) {
+		$total = 0;
+		$count = 0;
+		foreach ($xs as $x) {
+			if ($x > 0) {
+				$total += $x;
+				$count++;
+			}
+		}
+		return [$total, $count];
+	}
+
+	function sum_negative($xs) {
+		$total = 0;
+		$count = 0;
+		foreach ($xs as $x) {
+			if ($x < 0) {
+				$total += $x;
+				$count++;
+			}
+		}
+		return [$total, $count];
+	}
+
+	function small1($x) { return $x + 1; }
+	function small2($y) { return $y + 1; }
+	`, `<?php
+	class Stats {
+		public function sumPositive($values) {
+			$sum = 0;
+			$n = 0;
+			foreach ($values as $v) {
+				if ($v > 0) {
+					$sum += $v;
+					$n++;
+				}
+			}
+			return [$sum, $n];
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`sum_positive body is duplicated by Stats::sumPositive (file1.php:3)`)
+}
+
+func TestCacheDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "php-critic-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	src := filepath.Join(root, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(src, "a.php"), []byte(`<?php
+	class CachedConsts { const X = 1; }
+	function cached_sq($x) { return $x * $x; }
+	function cached_f($x) {
+		if (CachedConsts::X == 1) {}
+		return cached_sq($x) - cached_sq($x);
+	}
+	function cached_clone1($xs) {
+		$total = 0;
+		$count = 0;
+		foreach ($xs as $x) {
+			if ($x > 0) {
+				$total += $x;
+				$count++;
+			}
+		}
+		return [$total, $count];
+	}
+	function cached_clone2($xs) {
+		$total = 0;
+		$count = 0;
+		foreach ($xs as $x) {
+			if ($x > 0) {
+				$total += $x;
+				$count++;
+			}
+		}
+		return [$total, $count];
+	}
+	`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	once.Do(func() { go linter.MemoryLimiterThread() })
+	run := func() []*linter.Report {
+		meta.ResetInfo()
+		linter.ParseFilenames(linter.ReadFilenames([]string{src}, nil))
+		meta.SetIndexingComplete(true)
+		return linter.ParseFilenames(linter.ReadFilenames([]string{src}, nil))
+	}
+
+	// The first run fills the cache, the second one uses it.
+	// Both must ignore the php-critic indexes.
+	linter.CacheDir = filepath.Join(root, "cache")
+	for i := 0; i < 2; i++ {
+		matchReports(t, run())
+	}
+
+	linter.CacheDir = ""
+	matchReports(t, run(),
+		`always true condition`,
+		`suspiciously duplicated LHS and RHS of '-'`,
+		`cached_clone1 body is duplicated by cached_clone2`)
+}
+
+func TestDupCodeGroup(t *testing.T) {
+	var code strings.Builder
+	code.WriteString("<?php\n")
+	for i := 0; i < 8; i++ {
+		fmt.Fprintf(&code, `
+	function f%d($xs) {
+		$total = 0;
+		$count = 0;
+		foreach ($xs as $x) {
+			if ($x > 0) {
+				$total += $x;
+				$count++;
+			}
+		}
+		return [$total, $count];
+	}
+	`, i)
+	}
+	reports := singleFileReports(t, code.String())
+
+	matchReports(t, reports,
+		`f0 body is duplicated by f1 (first.php:15), f2 (first.php:27), f3 (first.php:39), f4 (first.php:51), f5 (first.php:63), 2 more at first.php:3`)
+}
+
+func TestConfigSettings(t *testing.T) {
+	dir, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := parseConfig([]byte(`
+checks:
+  badCond:
+    severity: error
+  dupSubExpr:
+    enabled: false
+  dupArg:
+    params:
+      functions: [array_diff]
+paths:
+  - glob: first.php
+    checks:
+      selfAssign: {enabled: false}
+`), false, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs = []*config{cfg}
+	defer func() { configs = nil }()
+
+	reports := singleFileReports(t, `<?php
+	function array_diff($x, $y) { return $x; }
+	function strcmp($x, $y) { return 0; }
+	function f($x, $y) {
+		$_ = 1 == 2;
+		$_ = $x - $x; // Disabled
+		$x = $x; // Disabled for this file
+		$_ = strcmp($x, $x); // Not in the functions list
+		$_ = array_diff($y, $y);
+	}
+	`)
+
+	matchReports(t, reports,
+		`ERROR   badCond: always false condition`,
+		`WARNING dupArg: suspiciously duplicated argument`)
+}
+
+func TestSuppressions(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f($x, $y) {
+		$x = $x; // php-critic:ignore selfAssign -- trailing comment
+		// php-critic:ignore selfAssign,dupSubExpr -- next statement (selfAssign is stale)
+		$y = $y - $y +
+			($x - $x);
+		$_ = $x - $x; // Not suppressed
+		if ($x) {
+			// php-critic:ignore dupSubExpr -- enclosing block
+			$_ = [$x - $x];
+		}
+		if ($y) { // php-critic:ignore dupSubExpr -- whole if statement
+			$_ = $y - $y;
+		}
+		$x = $x; // php-critic:ignore selfAssign
+		$x = $x; // php-critic:ignore selfAsign -- typo
+		# php-critic:ignore dupArg -- stale
+		$_ = $x - $x;
+		return [$x, $y];
+	}
+
+	/**
+	 * php-critic:ignore dupSubExpr -- the whole function
+	 */
+	function g($x) {
+		$_ = $x - $x;
+		return $x / $x;
+	}
+
+	class C {
+		/** php-critic:ignore selfAssign -- method */
+		public function m($x) {
+			$x = $x;
+			return $x;
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`suspiciously duplicated LHS and RHS of '-': '$x' at first.php:7`,
+		`suspicious self-assignment at first.php:15`,
+		`bad php-critic:ignore directive: missing reason, expected 'php-critic:ignore checks -- reason' at first.php:15`,
+		`suspicious self-assignment at first.php:16`,
+		`bad php-critic:ignore directive: unknown check "selfAsign" at first.php:16`,
+		`suspiciously duplicated LHS and RHS of '-': '$x' at first.php:18`,
+		`stale php-critic:ignore directive: no dupArg reports to suppress at first.php:18`,
+		`stale php-critic:ignore directive: no selfAssign reports to suppress at first.php:5`)
+}
+
+func TestSuppressionsOutsideComments(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	const php = 1, critic = 1, ignore = '';
+	function f($x) {
+		$_ = '// php-critic:ignore dupSubExpr -- in a string';
+		$_ = $x - $x;
+		$_ = <<<EOS
+# php-critic:ignore dupSubExpr -- in a heredoc
+EOS;
+		$_ = $x - $x;
+		$_ = $x ? $x
+			* php-critic:ignore . ' dupSubExpr -- in the code';
+		$_ = $x - $x;
+	}
+	`)
+
+	matchReports(t, reports,
+		`suspiciously duplicated LHS and RHS of '-': '$x' at first.php:5`,
+		`suspiciously duplicated LHS and RHS of '-': '$x' at first.php:9`,
+		`suspiciously duplicated LHS and RHS of '-': '$x' at first.php:12`)
+}
+
+func TestSuppressionsSingleFunc(t *testing.T) {
+	// The file root and the function have the same lines range here.
+	reports := singleFileReports(t, `<?php function f($x) {
+		// php-critic:ignore dupSubExpr -- next statement only
+		$_ = $x - $x;
+		$_ = $x / $x;
+	}`)
+
+	matchReports(t, reports,
+		`suspiciously duplicated LHS and RHS of '/': '$x' at first.php:4`)
+}
+
 func TestBadCondWhile(t *testing.T) {
 	reports := singleFileReports(t, `<?php
 	function define($name, $val) {};
@@ -91,6 +635,7 @@ func TestBadCondWhile(t *testing.T) {
 	do {} while (true); // Not OK
 	while (true) {} // OK
 	while (0 === 0) {} // Not OK
+	do {} while (false); // OK
 	`)
 
 	reports = filterReports(reports, "badCond")
@@ -175,9 +720,658 @@ func TestBadCondOr(t *testing.T) {
 	`)
 
 	matchReports(t, reports,
+		`always true condition`,
+		`duplicated condition, first occurrence at line 4`)
+}
+
+func TestBadCondRanges(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function in_array($needle, $haystack, $strict = false) {}
+	`, `<?php
+	function f($x, $y, $arr) {
+		$_ = $x > 0 && $y && $x < 0;
+		$_ = $x >= 10 && $x <= 5;
+		$_ = 5 > $x && $x == 7;
+		$_ = $x != 1 && $x == 1;
+		$_ = $x >= 1 && $x <= 1; // OK: $x == 1
+		$_ = $x > 1 && $x < 2; // OK: floats
+		$_ = $arr[0] > 1 && !($arr[0] > 0);
+		$_ = in_array($x, [1, 2, 3]) && $x > 5;
+
+		$_ = $x < 5 || $x >= 5;
+		$_ = $x != 1 || $y || $x != 2;
+		$_ = $x > 0 || $x <= 0.5;
+		$_ = $x > 0 || ($x < 1 && $y); // OK: $y is unknown
+		$_ = !in_array($x, [1, 2]) || $x == 1 || $x == 2;
+		$_ = in_array($x, [1], 1) || $x < 1 || $x > 1; // OK: $x can be '1'
+		$_ = in_array($x, [1], $y) || $x < 1 || $x > 1; // OK: $y can be true
+		$_ = in_array($x, [1], 0) || $x < 1 || $x > 1;
+
+		$_ = $x > 5 && $x > 3;
+		$_ = $x > 3 && $y > 1 && $x > 5;
+		$_ = $x > 5 || $x > 3;
+		$_ = $x == 1 || $x == 2 || $x == 3; // OK
+		$_ = $x > 0 && $x < 10 && $x != 20;
+		$_ = in_array($x, [1, 2]) && $x < 3;
+	}
+	`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always false condition`,
+		`always false condition`,
+		`always false condition`,
+		`always false condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`redundant sub-condition: implied by other && operands`,
+		`redundant sub-condition: implied by other && operands`,
+		`redundant sub-condition: covered by other || operands`,
+		`redundant sub-condition: implied by other && operands`,
+		`redundant sub-condition: implied by other && operands`)
+}
+
+func TestBadCondLooseCmp(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f() {
+		$_ = "1" == 1;
+		$_ = "abc" == 0;
+		$_ = "10" < "9";
+		$_ = 1.5 >= "1.5";
+		$_ = !0 != "0";
+		$_ = (1 <=> 2) == -1;
+	}`)
+
+	matchReports(t, reports,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`)
+}
+
+func TestBadCondArray(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function count($arr) {}
+	function in_array($needle, $haystack, $strict = false) {}
+	function array_key_exists($key, $arr) {}
+	`, `<?php
+	const MODES = ['a' => 1, '5' => 2, 6 => 3];
+	$x = 10;
+	if (in_array('x', ['a', 'b'])) {}
+	if (in_array('1', [1, 2])) {}
+	while (in_array('1', [1, 2], 1)) {}
+	if (in_array($x, ['a', 'b'])) {} // OK: unknown needle
+	$_ = count(MODES) == 3;
+	$_ = array_key_exists(7, MODES) || isset(MODES['a']);
+	$_ = MODES[5] === 2;
+	$_ = [1, 2] == [1 => 2, 0 => 1];
+	`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
 		`always true condition`)
 }
 
+func TestBadCondNull(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function define($name, $value) {}
+	function is_null($x) {}
+	define('null', 0);
+	define('false', 1 === 0);
+	`, `<?php
+	const NAME = 'x';
+	const NOTHING = null;
+	const OPTS = ['a' => null, 'b' => 1];
+	$x = 10;
+	if (NAME === null) {}
+	if (is_null(NOTHING)) {}
+	if (isset(OPTS['a'])) {}
+	if (isset(OPTS['b'])) {}
+	$_ = (NOTHING ?? 'y') === 'y';
+	$_ = (NAME ?: 'y') === 'y';
+	$_ = null == false;
+	$_ = NOTHING === $x; // OK: $x is unknown
+	`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`unreachable ternary branch: condition is always true`,
+		`always true condition`)
+}
+
+func TestBadCondArith(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f() {
+		$_ = 2 * 3 == 6;
+		$_ = 7 / 2 === 3;
+		$_ = 6 / 2 === 3;
+		$_ = 7.9 % 2 === 1;
+		$_ = 2 ** 3 !== 8;
+		$_ = 2 ** 63 === 2 ** 62 * 2;
+		$_ = 9223372036854775807 + 1 > 0;
+		$_ = (1 << 4 | 1) === 17;
+		$_ = (255 ^ 15) >= 240;
+		$_ = ~0 != -1;
+		$_ = +"12" <= 11;
+		$_ = "5" + "5" === 10;
+		$_ = 1 / 0 == 0; // OK: division by zero is not folded
+		$_ = 10 % 0 == 0; // OK: modulo by zero is not folded
+	}`)
+
+	matchReports(t, reports,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always false condition`,
+		`always true condition`)
+}
+
+func TestBadCondBuiltins(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function strlen($s) {}
+	function strtoupper($s) {}
+	function substr($s, $start, $length = null) {}
+	function sprintf($format, ...$args) {}
+	function implode($glue, $pieces) {}
+	function trim($s, $chars = "") {}
+	function max(...$args) {}
+	function intdiv($x, $y) {}
+	function array_keys($arr) {}
+	function count($arr) {}
+	function str_pad($s, $length, $pad = " ", $type = 1) {}
+	function dechex($x) {}
+	function unknown_func($x) {}
+	`, `<?php
+	namespace Foo;
+	/** @linter disable */
+	function strlen($s) {}
+	`, `<?php
+	function f() {
+		$_ = strlen("abc") === 3;
+		$_ = \strtoupper("abc") !== "ABC";
+		$_ = substr("hello", 1, 3) == "ell";
+		$_ = substr("hello", -3) == "xyz";
+		$_ = sprintf("%05.1f|%-3s|%'*4d|%x", 3.14159, "a", 7, 255) === "003.1|a  |***7|ff";
+		$_ = sprintf('%2$s %1$s', "a", "b") === "b a";
+		$_ = implode(",", [1, 2, 3]) === "1,2,3";
+		$_ = trim("xxabcxx", "a..x") == "";
+		$_ = max(1, 5, 3) === 5;
+		$_ = count(array_keys(["a" => 1, "b" => 2])) === 2;
+		$_ = str_pad("5", 3, "0", 0) === "005";
+		$_ = dechex(255) === "ff";
+		$_ = intdiv(1, 0) === 0; // OK: division by zero is not folded
+		$_ = unknown_func(1) === 1; // OK: not a registered function
+		$_ = sprintf("%d %d", 1) === "1"; // OK: not enough arguments
+	}
+
+	namespace Foo;
+
+	function g() {
+		$_ = strlen("abc") === 3; // OK: Foo\strlen is called
+		$_ = \strlen("abc") === 3;
+	}`)
+
+	matchReports(t, reports,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`)
+}
+
+func TestBadCondClassConst(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	namespace Lib;
+
+	interface Modes {
+		const DEFAULT_MODE = 'fast';
+	}
+
+	class Base implements Modes {
+		const LIMIT = 10;
+		const DOUBLE_LIMIT = self::LIMIT * 2;
+		const LOOP = self::LOOP;
+	}
+	`, `<?php
+	use Lib\Base;
+
+	class Derived extends Base {
+		const LIMIT = 20;
+		const NAME = 'derived';
+
+		public function f() {
+			$_ = self::MODE === 'x';
+			$_ = self::NAME === 'derived';
+			$_ = self::DEFAULT_MODE !== 'fast';
+			$_ = parent::LIMIT === 10;
+			$_ = self::LIMIT === 20;
+			$_ = self::DOUBLE_LIMIT === 20; // Base::LIMIT is used
+			$_ = Base::DOUBLE_LIMIT > 100;
+			$_ = self::class === 'Derived';
+			$_ = static::NAME === 'derived'; // OK: can be overridden
+			$_ = Base::LOOP === 1; // OK: recursive definition
+		}
+	}`)
+
+	matchReports(t, reports,
+		`Class constant \Derived::MODE does not exist`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`)
+}
+
+func TestBadCondPredefinedConst(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function define($name, $value) {}
+	define('PHP_INT_SIZE', 4);
+	define('PHP_INT_MAX', 0);
+	define('PHP_EOL', "");
+	define('PHP_MAJOR_VERSION', 0);
+	define('E_ALL', 0);
+	define('E_NOTICE', 0);
+	define('SORT_STRING', 0);
+	define('JSON_PRETTY_PRINT', 0);
+	`, `<?php
+	$_ = PHP_INT_SIZE === 4;
+	$_ = PHP_INT_MAX + 1 > PHP_INT_MAX;
+	$_ = \PHP_EOL === "\n";
+	$_ = (E_ALL & E_NOTICE) !== 0;
+	$_ = SORT_STRING == 2;
+	$_ = (JSON_PRETTY_PRINT | 1) === 129;
+	$_ = PHP_MAJOR_VERSION === 7;
+	`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`)
+}
+
+func TestBadCondMagicConst(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	namespace Foo;
+
+	trait T {
+		public function t() {
+			$_ = __TRAIT__ === 'Foo\\T';
+			$_ = __CLASS__ === 'Foo\\T'; // OK: depends on the class that uses T
+		}
+	}
+
+	class Bar {
+		public function m() {
+			$_ = __CLASS__ === 'Foo\\Bar';
+			$_ = __METHOD__ !== 'Foo\\Bar::m';
+			$_ = __FUNCTION__ === 'm';
+			$_ = __TRAIT__ === '';
+			$_ = function() {
+				$_ = __FUNCTION__ === 'm'; // OK: closure name is not folded
+			};
+		}
+	}
+
+	function f() {
+		$_ = __FUNCTION__ === 'Foo\\f';
+		$_ = __NAMESPACE__ === 'Foo';
+		$_ = __LINE__ === 26;
+		$_ = __FILE__ === '';
+		$_ = __DIR__ === '';
+	}
+
+	if (f()) {}
+	if (__FUNCTION__ === 'Foo\\f') {} // Root level __FUNCTION__ is ''
+	`)
+
+	matchReports(t, reports,
+		`always false condition at first.php:32`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always false condition`)
+}
+
+func TestBadCondNumericLiterals(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f($x) {
+		$_ = (0xFF00 & 0x00FF) !== 0;
+		$_ = ($x & 0xFF00) === 0x10000; // OK: depends on $x
+		$_ = 0b1010 === 10;
+		$_ = 0777 == 511;
+		$_ = 9223372036854775808 === 9223372036854775807 + 1;
+		$_ = 0xFFFFFFFFFFFFFFFF < 0x7FFFFFFFFFFFFFFF;
+		$_ = -9223372036854775808 === ~0x7FFFFFFFFFFFFFFF;
+	}`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always false condition`,
+		`always false condition`)
+}
+
+func TestBadCondCasts(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f() {
+		$_ = (int)"12abc" === 12;
+		$_ = "5" + 3 !== 8;
+		$_ = (string)1.5 === "1.5";
+		$_ = (bool)"0.0" == 1;
+		$_ = (string)-0.0 === "-0";
+		$_ = (float)"1e3" === 1000.0;
+		$_ = (string)(0.1 + 0.2) == "0.3";
+		$_ = (array)"a" === ["a"];
+		$_ = "x" . 2.50 === "x2.5";
+		$_ = (int)$x === 0; // OK: unknown operand
+	}`)
+
+	matchReports(t, reports,
+		`always true condition`,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`Undefined variable: x`)
+}
+
+func TestDeadBranch(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function define($name, $value) {}
+	define('null', 0);
+	`, `<?php
+	const DEBUG = 0;
+	const OPTS = ['a' => 1, 'b' => null];
+	$_ = DEBUG ? 'debug' : 'release';
+	$_ = !DEBUG ? 'release' : 'debug';
+	$_ = 'x' ?: 'y';
+	$_ = DEBUG ?: 'y'; // OK: both operands are evaluated
+	$_ = OPTS['a'] ?? 0;
+	$_ = OPTS['b'] ?? 0; // OK: the element is null
+	$_ = OPTS['c'] ?? 0; // OK: the key is missing
+	$_ = 10 ?? 0;
+	$_ = null ?? 0; // OK
+	$_ = (OPTS['c'] ?? 5) === 5;
+
+	function f($x) {
+		$_ = $x ? 1 : 2; // OK: unknown condition
+		$_ = $x ?? 0; // OK: unknown operand
+	}
+	`)
+
+	matchReports(t, reports,
+		`unreachable ternary branch: condition is always false`,
+		`unreachable ternary branch: condition is always true`,
+		`unreachable ternary branch: condition is always true`,
+		`unreachable ?? operand: left operand is never null`,
+		`unreachable ?? operand: left operand is never null`,
+		`always true condition`)
+}
+
+func TestBadCondLocals(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function extract($arr) {}
+	function update(&$x) {}
+	function read($x) {}
+	function str_replace($search, $replace, $subject, &$count = null) {}
+	`, `<?php
+	function f($cond, $arr) {
+		$limit = 10;
+		if ($limit > 20) {} // BAD
+		$limit = $limit * 3;
+		if ($limit > 20) {} // BAD
+
+		$mode = 'a';
+		if ($cond) {
+			$mode = 'b';
+		}
+		if ($mode == 'c') {} // OK: 'a' or 'b'
+
+		$n = 1;
+		if ($cond) {
+			$n = 1;
+		} else {
+			$n += 0;
+		}
+		if ($n === 1) {} // BAD
+
+		if ($cond) {
+			$once = 1;
+		}
+		if ($once == 1) {} // OK: not assigned on the other path
+
+		$i = 0;
+		while ($i < 10) { // OK: assigned inside the loop
+			$i++;
+		}
+		$k = 5;
+		foreach ($arr as $_) {
+			if ($k == 5) {} // BAD
+		}
+
+		$r = 1;
+		$ref = &$r;
+		$ref = 2;
+		read($ref);
+		if ($r == 1) {} // OK: referenced
+
+		$u = 1;
+		update($u);
+		if ($u == 1) {} // OK: passed by reference
+		$w = 1;
+		read($w);
+		if ($w == 1) {} // BAD
+
+		$x = 1;
+		$cond && $x = 2;
+		if ($x == 1) {} // OK: conditional assignment
+
+		$v = 1;
+		$items = [&$v];
+		$items[0] = 2;
+		read($items);
+		if ($v === 1) {} // OK: referenced by the array item
+
+		$c = 0;
+		$_ = str_replace('a', 'b', $arr, $c);
+		if ($c === 0) {} // OK: passed by reference to a builtin
+	}
+
+	function g($name) {
+		global $glob;
+		$glob = 1;
+		if ($glob == 1) {} // OK: global
+		$y = 1;
+		$$name = 2;
+		if ($y == 1) {} // OK: dynamic assignment
+	}
+
+	function h($arr) {
+		$z = 1;
+		extract($arr);
+		if ($z == 1) {} // OK: extract()
+	}
+
+	function closures() {
+		$a = 1;
+		$_ = function () use ($a) {
+			if ($a == 1) {} // BAD
+		};
+		$b = 1;
+		$_ = function () use (&$b) {
+			$b = 2;
+		};
+		if ($b == 1) {} // OK: captured by reference
+	}
+	`)
+
+	matchReports(t, reports,
+		`always false condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`always true condition`,
+		`'+= 0' has no effect`,
+		`Variable might have not been defined: once`,
+		`Unused variable once`)
+}
+
+func TestDeadBranchElseIf(t *testing.T) {
+	reports := singleFileReports(t, `<?php
+	function f($x, $y, $a) {
+		if ($x > 10) {
+			$_ = 1;
+		} elseif ($x > 20) {
+			$_ = 2;
+		}
+
+		if ($a == 1) {
+			$_ = 3;
+		} elseif ($a == 2) {
+			$_ = 4;
+		} elseif ($a == 1) {
+			$_ = 5;
+		}
+
+		if ($x > 0 || $y) {
+			$_ = 6;
+		} elseif ($x > 5 && $y) {
+			$_ = 7;
+		}
+
+		if ($x < 0) {
+			$_ = 8;
+		} elseif ($x >= 0) {
+			$_ = 9;
+		} else {
+			$_ = 10;
+		}
+
+		if ($x < 0) {
+			$_ = 11;
+		} elseif ($x == 0) {
+			$_ = 12;
+		} elseif ($x > 0) {
+			$_ = 13;
+		}
+
+		if ($x < 0 && $y) {
+			$_ = 14;
+		} elseif ($x < -5) { // OK: $y can be false
+			$_ = 15;
+		}
+
+		if ($x > 10) {
+			$_ = 16;
+		} elseif ($y > 20) { // OK: different subjects
+			$_ = 17;
+		} elseif ($x > 5) {
+			$_ = 18;
+		}
+	}
+	`)
+
+	matchReports(t, reports,
+		`unreachable elseif branch: condition is excluded by previous conditions`,
+		`duplicated condition, first occurrence at line 9`,
+		`unreachable elseif branch: condition is excluded by previous conditions`,
+		`always true condition: implied by previous conditions`,
+		`always true condition: implied by previous conditions`)
+}
+
+func TestDupCond(t *testing.T) {
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function rand() {}
+	`, `<?php
+	function f($x, $y, $z) {
+		if ($x && $y) {
+			$_ = 1;
+		} elseif ($z) {
+			$_ = 2;
+		} elseif ($y && $x) {
+			$_ = 3;
+		}
+
+		if (rand() == 1) {
+			$_ = 4;
+		} elseif (rand() == 1) { // OK: impure call
+			$_ = 5;
+		}
+
+		$_ = $x || $y || $z || $x;
+		$_ = $x && ($y && $z) && $z;
+		$_ = $x && $y || $z && $x || $y && $x;
+		$_ = $x === $y && $y === $x;
+		$_ = $x && $y || $x; // OK: different chains
+	}
+	`)
+
+	matchReports(t, reports,
+		`duplicated condition, first occurrence at line 3`,
+		`duplicated condition, first occurrence at line 17`,
+		`duplicated condition, first occurrence at line 18`,
+		`duplicated condition, first occurrence at line 19`,
+		`duplicated condition, first occurrence at line 20`)
+}
+
 func TestSimplifyStrcmp(t *testing.T) {
 	reports := multiFileReports(t, `<?php
 	/** @linter disable */
@@ -187,13 +1381,67 @@ func TestSimplifyStrcmp(t *testing.T) {
 		$_ = strcmp($s1, $s2) === 0;
 		$_ = strcmp($s1, $s2) < 0;
 		$_ = strcmp($s1, $s2) > 0;
+		$_ = strcmp($s1[0], $s2 . 'x') === 0;
+		$_ = strcmp($s1, $s2, 'extra') < 0;
 	}
 	`)
 
 	matchReports(t, reports,
-		`can replace 'strcmp(s1, s2) === 0' with 's1 === s2'`,
-		`can replace 'strcmp(s1, s2) < 0' with 's1 < s2'`,
-		`can replace 'strcmp(s1, s2) > 0' with 's1 > s2'`)
+		`can replace 'strcmp($s1, $s2) === 0' with '$s1 === $s2'`,
+		`can replace 'strcmp($s1, $s2) < 0' with '$s1 < $s2'`,
+		`can replace 'strcmp($s1, $s2) > 0' with '$s1 > $s2'`,
+		`can replace 'strcmp($s1[0], $s2 . 'x') === 0' with '$s1[0] === $s2 . 'x''`,
+		`can replace 'strcmp($s1, $s2, 'extra') < 0' with a direct '<' comparison`)
+}
+
+func TestFixes(t *testing.T) {
+	fixes.take()
+	src := `<?php
+	function f(string $s1, string $s2, $xs) {
+		$_ = strcmp($s1, $s2) === 0;
+		$_ = strcmp($xs[0], $s1 . 'x') < 0; // Not fixable: numeric strings
+		$_ = strcmp($s1 ?: 'x', $s2) === 0;
+		$_ = strncmp($s1, 'abc', 2);
+		$_ = strcmp($xs[0], $s1) === 0; // Not fixable: $xs[0] can be an int
+		// php-critic:ignore simplify -- suppressed reports are not fixed
+		$_ = strcmp($s1, $s2) === 0;
+	}
+	`
+	reports := multiFileReports(t, `<?php
+	/** @linter disable */
+	function strcmp($s1, $s2) {}
+	function strncmp($s1, $s2, $n) {}
+	`, src)
+	matchReports(t, reports,
+		`can replace 'strcmp($s1, $s2) === 0' with '$s1 === $s2'`,
+		`can replace 'strcmp($xs[0], $s1 . 'x') < 0' with '$xs[0] < $s1 . 'x''`,
+		`can replace 'strcmp($s1 ?: 'x', $s2) === 0' with '($s1 ?: 'x') === $s2'`,
+		`expected length arg to be 3, got 2`,
+		`can replace 'strcmp($xs[0], $s1) === 0' with '$xs[0] === $s1'`)
+
+	edits := fixes.take()
+	code, applied := applyEdits([]byte(src), edits["file1.php"])
+	want := `<?php
+	function f(string $s1, string $s2, $xs) {
+		$_ = $s1 === $s2;
+		$_ = strcmp($xs[0], $s1 . 'x') < 0; // Not fixable: numeric strings
+		$_ = ($s1 ?: 'x') === $s2;
+		$_ = strncmp($s1, 'abc', 3);
+		$_ = strcmp($xs[0], $s1) === 0; // Not fixable: $xs[0] can be an int
+		// php-critic:ignore simplify -- suppressed reports are not fixed
+		$_ = strcmp($s1, $s2) === 0;
+	}
+	`
+	if string(code) != want {
+		t.Errorf("fixed code mismatch:\nhave:\n%s\nwant:\n%s", code, want)
+	}
+	var fixed []string
+	for _, e := range applied {
+		fixed = append(fixed, fmt.Sprintf("%s:%d", e.checkName, e.line))
+	}
+	if have, want := strings.Join(fixed, " "), "simplify:3 simplify:5 badCall:6"; have != want {
+		t.Errorf("fixed reports mismatch: have %s, want %s", have, want)
+	}
 }
 
 func TestArgOrder(t *testing.T) {
@@ -236,11 +1484,26 @@ func TestBadCallStrncmp(t *testing.T) {
 		$_ = strncmp($s1, "\n\\\x11", 0+1+1+1);
 		$_ = strncmp($s1, ''."a"."b", 3); // BAD
 		$_ = strncmp("ab", $s1, 1+3); // BAD
+		$_ = strncmp($s1, "\t\u{1F600}\101", 6);
+		$_ = strncmp($s1, "\q\0", 3);
+		$_ = strncmp($s1, '\t', 2);
+		$prefix = 'abc';
+		$_ = strncmp($s1, $prefix, 2); // BAD
+		$_ = strncmp($s1, <<<EOT
+\e\v"
+EOT
+, 3);
+		$_ = strncmp($s1, <<<'EOT'
+a\tb
+EOT
+, 3); // BAD
 	}
 	`)
 	matchReports(t, reports,
 		`expected length arg to be 2, got 3`,
-		`expected length arg to be 2, got 4`)
+		`expected length arg to be 2, got 4`,
+		`expected length arg to be 3, got 2`,
+		`expected length arg to be 4, got 3`)
 }
 
 func TestDupArgStrncmp(t *testing.T) {
@@ -358,11 +1621,30 @@ func matchReports(t *testing.T, reports []*linter.Report, expect ...string) {
 	}
 }
 
+func TestMain(m *testing.M) {
+	// Node positions are parsed from the files on disk,
+	// so the test files are written to the temporary working directory.
+	dir, err := ioutil.TempDir("", "php-critic-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 var once sync.Once
 
 func testParse(t *testing.T, filename string, contents string) (rootNode node.Node, w *linter.RootWalker) {
 	once.Do(func() { go linter.MemoryLimiterThread() })
 
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	var err error
 	rootNode, w, err = linter.ParseContents(filename, []byte(contents), "UTF-8", nil)
 	if err != nil {
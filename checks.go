@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/VKCOM/noverify/src/linter"
+	"github.com/VKCOM/noverify/src/meta"
+	"github.com/z7zmey/php-parser/node"
+)
+
+// Check tags.
+const (
+	tagBug      = "bug"
+	tagStyle    = "style"
+	tagPerf     = "perf"
+	tagSecurity = "security"
+)
+
+// checkInfo describes a php-critic check.
+type checkInfo struct {
+	Name        string   `json:"name"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+
+	// Severity is a default report level, like linter.LevelWarning.
+	Severity int `json:"-"`
+
+	// Before is a code example that triggers the check.
+	Before string `json:"before"`
+	// After is a fixed version of the Before example.
+	After string `json:"after"`
+
+	// Params are the check parameters that can be set in the config.
+	Params []*checkParam `json:"params,omitempty"`
+}
+
+// Check param kinds.
+const (
+	paramInt     = "int"
+	paramStrings = "strings"
+)
+
+// checkParam describes a check parameter.
+type checkParam struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// param returns the check param with the given name or nil.
+func (c *checkInfo) param(name string) *checkParam {
+	for _, p := range c.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// checks is a registry of all php-critic checks, sorted by name.
+var checks = []*checkInfo{
+	{
+		Name:        "argOrder",
+		Summary:     "Detects suspicious arguments order",
+		Description: "Reports calls where a string literal is passed as a haystack and a non-literal value is passed as a needle.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `strpos('/', $s)`,
+		After:       `strpos($s, '/')`,
+	},
+	{
+		Name:        "badCall",
+		Summary:     "Detects suspicious function call arguments",
+		Description: "Reports function calls with arguments that don't match the other arguments, like the strncmp length that differs from the constant string length.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `strncmp($s, 'http://', 6)`,
+		After:       `strncmp($s, 'http://', 7)`,
+	},
+	{
+		Name:        "badCond",
+		Summary:     "Detects conditions that are always true or always false",
+		Description: "Conditions are evaluated with PHP comparison semantics using the known constants and local variables values. Numeric range contradictions inside && and || chains are reported too.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `if ($x > 10 && $x < 5) {}`,
+		After:       `if ($x > 10 || $x < 5) {}`,
+	},
+	{
+		Name:        "badIgnore",
+		Summary:     "Detects malformed php-critic:ignore directives",
+		Description: "Every suppression directive must list the suppressed checks and explain the reason after the -- separator. Malformed directives suppress nothing.",
+		Tags:        []string{tagStyle},
+		Severity:    linter.LevelWarning,
+		Before:      `$x = $x; // php-critic:ignore selfAssign`,
+		After:       `$x = $x; // php-critic:ignore selfAssign -- forces the copy-on-write`,
+	},
+	{
+		Name:        "deadBranch",
+		Summary:     "Detects branches that can never be executed",
+		Description: "Reports unreachable ternary and ?? operands, elseif branches excluded by the previous conditions and switch cases shadowed by the preceding cases.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `if ($x > 10) { f(); } elseif ($x > 20) { g(); }`,
+		After:       `if ($x > 20) { g(); } elseif ($x > 10) { f(); }`,
+	},
+	{
+		Name:        "dupArg",
+		Summary:     "Detects suspicious duplicated arguments",
+		Description: "Reports calls of functions like strcmp and min where the same expression is passed as both arguments.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `strcmp($s, $s)`,
+		After:       `strcmp($s, $t)`,
+		Params: []*checkParam{
+			{Name: "functions", Kind: paramStrings, Description: "Functions whose first two arguments are checked (strcmp, strncmp, strpos, min, max and str_replace by default)"},
+		},
+	},
+	{
+		Name:        "dupBranchBody",
+		Summary:     "Detects if-else branches and switch cases with identical bodies",
+		Description: "Identical if-else branches usually indicate a copy-paste error. Identical switch case bodies can be merged into one case.",
+		Tags:        []string{tagBug, tagStyle},
+		Severity:    linter.LevelWarning,
+		Before:      `if ($cond) { f(); } else { f(); }`,
+		After:       `if ($cond) { f(); } else { g(); }`,
+	},
+	{
+		Name:        "dupCase",
+		Summary:     "Detects duplicated switch case labels",
+		Description: "Switch uses the loose comparison, so labels like 1 and '1' are duplicates. Only the first of them can ever be matched.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      "switch ($x) {\ncase 1: return 'a';\ncase '1': return 'b';\n}",
+		After:       "switch ($x) {\ncase 1: return 'a';\ncase 2: return 'b';\n}",
+	},
+	{
+		Name:        "dupCode",
+		Summary:     "Detects functions and methods with duplicated bodies",
+		Description: "Function bodies are compared across the whole project. Bodies that differ only in the variable and member names are considered to be clones. Every group of clones is reported once, at its first function. The detection is disabled if the -cache-dir flag is set.",
+		Tags:        []string{tagStyle},
+		Severity:    linter.LevelDoNotReject,
+		Before:      "function sumA($xs) { $s = 0; foreach ($xs as $x) { $s += $x; } return $s; }\nfunction sumB($ys) { $t = 0; foreach ($ys as $y) { $t += $y; } return $t; }",
+		After:       "function sumA($xs) { $s = 0; foreach ($xs as $x) { $s += $x; } return $s; }\nfunction sumB($ys) { return sumA($ys); }",
+		Params: []*checkParam{
+			{Name: "minGroup", Kind: paramInt, Description: "Minimal number of clones to report (-clone-min-group flag value by default)"},
+			{Name: "minSize", Kind: paramInt, Description: "Minimal function body size in AST nodes (-clone-min-size flag value by default)"},
+		},
+	},
+	{
+		Name:        "dupCond",
+		Summary:     "Detects duplicated conditions",
+		Description: "Reports duplicated conditions of if-elseif chains and duplicated operands of && and || chains. Operands order of the commutative operators is ignored.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `if ($x == 1) { f(); } elseif ($x == 1) { g(); }`,
+		After:       `if ($x == 1) { f(); } elseif ($x == 2) { g(); }`,
+	},
+	{
+		Name:        "dupSubExpr",
+		Summary:     "Detects suspicious duplicated operands",
+		Description: "Reports binary operators like - and == that have the same operands on both sides. Operands with side effects are never reported.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `$x->a == $x->a`,
+		After:       `$x->a == $y->a`,
+	},
+	{
+		Name:        "noop",
+		Summary:     "Detects compound assignments that have no effect",
+		Description: "Reports += 0 and *= 1 on numbers and .= '' on strings. Assignments that convert the type of the variable are not reported.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `$x *= 1;`,
+		After:       `$x *= 10;`,
+	},
+	{
+		Name:        "redundantCond",
+		Summary:     "Detects redundant && and || operands",
+		Description: "Reports numeric comparisons that are implied by the other operands of the && chain or that are covered by the other operands of the || chain.",
+		Tags:        []string{tagStyle},
+		Severity:    linter.LevelWarning,
+		Before:      `$x > 10 && $x > 5`,
+		After:       `$x > 10`,
+	},
+	{
+		Name:        "selfAssign",
+		Summary:     "Detects self-assignments",
+		Description: "Reports assignments of the expression to itself, which usually indicate a typo.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `$this->a = $this->a;`,
+		After:       `$this->a = $a;`,
+	},
+	{
+		Name:        "simplify",
+		Summary:     "Detects expressions that can be simplified",
+		Description: "Reports strcmp calls that are compared with 0 and can be replaced with the comparison operators.",
+		Tags:        []string{tagStyle, tagPerf},
+		Severity:    linter.LevelDoNotReject,
+		Before:      `strcmp($s1, $s2) === 0`,
+		After:       `$s1 === $s2`,
+	},
+	{
+		Name:        "sloppyArg",
+		Summary:     "Detects deprecated or discouraged arguments",
+		Description: "Reports define calls with case_insensitive argument, which is deprecated since PHP 7.3.",
+		Tags:        []string{tagBug},
+		Severity:    linter.LevelWarning,
+		Before:      `define('X', 1, true);`,
+		After:       `define('X', 1);`,
+	},
+	{
+		Name:        "staleIgnore",
+		Summary:     "Detects php-critic:ignore directives that suppress nothing",
+		Description: "Reports suppression directives whose checks have no reports in the suppressed code. Such directives are usually left after the code was fixed.",
+		Tags:        []string{tagStyle},
+		Severity:    linter.LevelWarning,
+		Before:      "// php-critic:ignore dupArg -- legacy code\n$x = strcmp($a, $b);",
+		After:       `$x = strcmp($a, $b);`,
+	},
+}
+
+var checksByName = func() map[string]*checkInfo {
+	m := make(map[string]*checkInfo, len(checks))
+	for _, c := range checks {
+		m[c.Name] = c
+	}
+	return m
+}()
+
+// reporter is implemented by the linter contexts.
+type reporter interface {
+	Report(n node.Node, level int, checkName, msg string, args ...interface{})
+}
+
+// report reports n unless the check is disabled by the file settings
+// or suppressed by the inline directive.
+// Report level is the check severity.
+// Returns whether n was reported.
+//
+// info can be nil if the file info is not available.
+func report(r reporter, info *fileInfo, n node.Node, checkName, format string, args ...interface{}) bool {
+	if _, ok := checksByName[checkName]; !ok {
+		panic(fmt.Sprintf("report: unregistered check %q", checkName))
+	}
+	if !meta.IsIndexingComplete() {
+		// Linter ignores the reports during the indexing.
+		return false
+	}
+	var settings fileSettings
+	if info != nil {
+		settings = info.settings
+		if pos := info.nodePositions()[n]; pos != nil && info.suppressions.suppress(checkName, pos.StartLine) {
+			return false
+		}
+	}
+	if !settings.enabled(checkName) {
+		return false
+	}
+	r.Report(n, settings.severity(checkName), checkName, format, args...)
+	return true
+}
+
+// severityNames maps report levels to their names.
+var severityNames = map[int]string{
+	linter.LevelError:       "error",
+	linter.LevelWarning:     "warning",
+	linter.LevelInformation: "info",
+	linter.LevelHint:        "hint",
+	linter.LevelDoNotReject: "maybe",
+}
+
+// severityByName returns a report level by its name or 0.
+func severityByName(name string) int {
+	for level, levelName := range severityNames {
+		if levelName == name {
+			return level
+		}
+	}
+	return 0
+}
+
+// severityList returns all severity names ordered by their levels.
+func severityList() []string {
+	levels := make([]int, 0, len(severityNames))
+	for level := range severityNames {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = severityNames[level]
+	}
+	return names
+}
+
+// printChecks prints the checks registry to w in the given format.
+// Supported formats are "text", "json" and "markdown".
+func printChecks(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return printChecksText(w)
+	case "json":
+		return printChecksJSON(w)
+	case "markdown":
+		return printChecksMarkdown(w)
+	default:
+		return fmt.Errorf("unknown checks list format %q (expected text, json or markdown)", format)
+	}
+}
+
+func printChecksText(w io.Writer) error {
+	for _, c := range checks {
+		_, err := fmt.Fprintf(w, "%s [%s] (%s)\n\t%s\n",
+			c.Name, strings.Join(c.Tags, ", "), severityNames[c.Severity], c.Summary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printChecksJSON(w io.Writer) error {
+	type jsonCheck struct {
+		*checkInfo
+		Severity string `json:"severity"`
+	}
+	list := make([]jsonCheck, len(checks))
+	for i, c := range checks {
+		list[i] = jsonCheck{checkInfo: c, Severity: severityNames[c.Severity]}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+func printChecksMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# php-critic checks\n\n")
+	b.WriteString("| Name | Tags | Severity | Summary |\n")
+	b.WriteString("|------|------|----------|---------|\n")
+	for _, c := range checks {
+		fmt.Fprintf(&b, "| [%s](#%s) | %s | %s | %s |\n",
+			c.Name, strings.ToLower(c.Name), strings.Join(c.Tags, ", "), severityNames[c.Severity], c.Summary)
+	}
+	for _, c := range checks {
+		fmt.Fprintf(&b, "\n## %s\n\n", c.Name)
+		fmt.Fprintf(&b, "%s.\n\n%s\n\n", c.Summary, c.Description)
+		fmt.Fprintf(&b, "**Tags**: %s. **Severity**: %s.\n\n", strings.Join(c.Tags, ", "), severityNames[c.Severity])
+		if len(c.Params) != 0 {
+			b.WriteString("Parameters:\n\n")
+			for _, p := range c.Params {
+				fmt.Fprintf(&b, "- `%s` (%s): %s.\n", p.Name, p.Kind, p.Description)
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Non-compliant code:\n\n```php\n%s\n```\n\n", c.Before)
+		fmt.Fprintf(&b, "Compliant code:\n\n```php\n%s\n```\n", c.After)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestChecksRegistry(t *testing.T) {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = c.Name
+		if c.Summary == "" || c.Description == "" || c.Before == "" || c.After == "" {
+			t.Errorf("%s: missing docs", c.Name)
+		}
+		if len(c.Tags) == 0 {
+			t.Errorf("%s: no tags", c.Name)
+		}
+		if _, ok := severityNames[c.Severity]; !ok {
+			t.Errorf("%s: unexpected severity %d", c.Name, c.Severity)
+		}
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("checks are not sorted by name")
+	}
+	if len(checksByName) != len(checks) {
+		t.Errorf("duplicated check names")
+	}
+}
+
+func TestPrintChecks(t *testing.T) {
+	for _, format := range []string{"text", "json", "markdown"} {
+		var b strings.Builder
+		if err := printChecks(&b, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for _, c := range checks {
+			if !strings.Contains(b.String(), c.Name) {
+				t.Errorf("%s: %s is not printed", format, c.Name)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := printChecks(&b, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var list []struct {
+		Name     string `json:"name"`
+		Severity string `json:"severity"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(checks) || list[0].Name != "argOrder" || list[0].Severity != "warning" {
+		t.Errorf("unexpected JSON output: %s", b.String())
+	}
+
+	if err := printChecks(&b, "xml"); err == nil {
+		t.Errorf("expected an error for the unknown format")
+	}
+}
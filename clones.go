@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VKCOM/noverify/src/linter"
+	"github.com/VKCOM/noverify/src/meta"
+	"github.com/quasilyte/php-critic/internal/astcmp"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/walker"
+)
+
+// cloneComparer defines which function bodies are considered to be clones.
+// Bodies that differ only in the local names are still clones.
+var cloneComparer = astcmp.Config{AbstractIdentifiers: true}
+
+// cloneIndex groups the functions and methods by their normalized bodies.
+// Functions are grouped by the body hashes, and the hash collisions
+// are resolved by the body keys comparison.
+//
+// It's filled by the cloneChecker during the indexing and
+// used to report the clones after the indexing is complete.
+type cloneIndex struct {
+	// minGroup is a minimal number of clones that is reported.
+	// minSize is a minimal function body size (in AST nodes)
+	// that is considered for the clone detection.
+	// Both are defaults that can be overridden by the config.
+	minGroup int
+	minSize  int
+
+	mu     sync.Mutex
+	groups map[uint64][]*cloneFunc
+	// linting is set once the clones are being reported.
+	// New indexing pass resets the index.
+	linting bool
+}
+
+// cloneFunc is a function or method that is recorded to the cloneIndex.
+type cloneFunc struct {
+	name     string
+	filename string
+	line     int
+
+	// key is the function body key, see astcmp.Config.Key.
+	key string
+}
+
+func (f *cloneFunc) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.name, f.filename, f.line)
+}
+
+func (idx *cloneIndex) add(hash uint64, f *cloneFunc) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.linting {
+		idx.groups = nil
+		idx.linting = false
+	}
+	if idx.groups == nil {
+		idx.groups = make(map[uint64][]*cloneFunc)
+	}
+	idx.groups[hash] = append(idx.groups[hash], f)
+}
+
+// group returns the clones of f sorted by the location (f included),
+// if there are at least minGroup functions in the group.
+func (idx *cloneIndex) group(hash uint64, f *cloneFunc, minGroup int) []*cloneFunc {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.linting = true
+	if minGroup <= 0 || len(idx.groups[hash]) < minGroup {
+		return nil
+	}
+	var sorted []*cloneFunc
+	for _, clone := range idx.groups[hash] {
+		if clone.key == f.key {
+			sorted = append(sorted, clone)
+		}
+	}
+	if len(sorted) < minGroup {
+		return nil
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].filename != sorted[j].filename {
+			return sorted[i].filename < sorted[j].filename
+		}
+		return sorted[i].line < sorted[j].line
+	})
+	return sorted
+}
+
+// maxListedClones is a maximal number of clones listed in the report.
+const maxListedClones = 5
+
+// cloneChecker finds functions and methods with duplicated bodies.
+type cloneChecker struct {
+	linter.RootCheckerDefaults
+
+	ctxt *linter.RootContext
+	idx  *cloneIndex
+}
+
+// fileInfo returns the current file info or nil.
+func (c *cloneChecker) fileInfo() *fileInfo {
+	info, _ := c.ctxt.State()[fileInfoKey].(*fileInfo)
+	return info
+}
+
+// settings returns the config settings for the current file.
+func (c *cloneChecker) settings() fileSettings {
+	if info := c.fileInfo(); info != nil {
+		return info.settings
+	}
+	return nil
+}
+
+func (c *cloneChecker) report(n node.Node, checkName, format string, args ...interface{}) {
+	report(c.ctxt, c.fileInfo(), n, checkName, format, args...)
+}
+
+func (c *cloneChecker) BeforeEnterNode(w walker.Walkable) {
+	st := c.ctxt.ClassParseState()
+
+	switch n := w.(type) {
+	case *stmt.Function:
+		name := n.FunctionName.(*node.Identifier)
+		fqn := strings.TrimPrefix(st.Namespace+`\`+name.Value, `\`)
+		body := &stmt.Function{Params: n.Params, Stmts: n.Stmts}
+		c.checkClones(n, name, fqn, body)
+	case *stmt.ClassMethod:
+		name := n.MethodName.(*node.Identifier)
+		fqn := strings.TrimPrefix(st.CurrentClass, `\`) + "::" + name.Value
+		body := &stmt.Function{Params: n.Params, Stmts: n.Stmts}
+		c.checkClones(n, name, fqn, body)
+	}
+}
+
+// checkClones records fn during the indexing and reports
+// its clones after the indexing is complete.
+// Only the first function of the clones group is reported.
+//
+// body is a function node that holds only fn params and statements,
+// so the function names and modifiers are not compared.
+func (c *cloneChecker) checkClones(fn, name node.Node, fqn string, body *stmt.Function) {
+	if cachedIndexing() {
+		return
+	}
+	settings := c.settings()
+	if nodeCount(body) < settings.intParam("dupCode", "minSize", c.idx.minSize) {
+		return
+	}
+	f := &cloneFunc{
+		name:     fqn,
+		filename: c.ctxt.Filename(),
+		key:      cloneComparer.Key(body),
+	}
+	if info := c.fileInfo(); info != nil {
+		if pos := info.nodePositions()[fn]; pos != nil {
+			f.line = pos.StartLine
+		}
+	}
+	hash := astcmp.KeyHash(f.key)
+
+	if !meta.IsIndexingComplete() {
+		c.idx.add(hash, f)
+		return
+	}
+	minGroup := settings.intParam("dupCode", "minGroup", c.idx.minGroup)
+	group := c.idx.group(hash, f, minGroup)
+	// Every group is reported once, at its first function.
+	if len(group) == 0 || *group[0] != *f {
+		return
+	}
+	clones := group[1:]
+	var others []string
+	for i, clone := range clones {
+		if i == maxListedClones {
+			others = append(others, fmt.Sprintf("%d more", len(clones)-i))
+			break
+		}
+		others = append(others, clone.String())
+	}
+	c.report(name, "dupCode",
+		"%s body is duplicated by %s", fqn, strings.Join(others, ", "))
+}
+
+// nodeCount returns the number of nodes inside n tree.
+func nodeCount(n node.Node) int {
+	var counter nodeCounter
+	n.Walk(&counter)
+	return int(counter)
+}
+
+type nodeCounter int
+
+func (c *nodeCounter) EnterNode(w walker.Walkable) bool {
+	*c++
+	return true
+}
+
+func (c *nodeCounter) GetChildrenVisitor(key string) walker.Visitor { return c }
+func (c *nodeCounter) LeaveNode(w walker.Walkable)                  {}
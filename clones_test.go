@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCloneIndexHashCollision(t *testing.T) {
+	idx := &cloneIndex{}
+	f1 := &cloneFunc{name: "f1", filename: "a.php", line: 1, key: "body1"}
+	f2 := &cloneFunc{name: "f2", filename: "a.php", line: 2, key: "body2"}
+	f3 := &cloneFunc{name: "f3", filename: "b.php", line: 1, key: "body1"}
+	// All bodies have the same hash, but only f1 and f3 are equal.
+	for _, f := range []*cloneFunc{f1, f2, f3} {
+		idx.add(1, f)
+	}
+
+	if group := idx.group(1, f2, 2); group != nil {
+		t.Errorf("f2 has no clones, but got %v", group)
+	}
+	group := idx.group(1, f3, 2)
+	if len(group) != 2 || group[0] != f1 || group[1] != f3 {
+		t.Errorf("f3 clones mismatch: got %v", group)
+	}
+	if group := idx.group(1, f1, 3); group != nil {
+		t.Errorf("f1 group is smaller than 3, but got %v", group)
+	}
+}
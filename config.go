@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configFilenames are the project config file names in the lookup order.
+var configFilenames = []string{".php-critic.yml", ".php-critic.yaml", ".php-critic.json"}
+
+// configs are the loaded project configs.
+// A file is analyzed with the config from its nearest parent directory.
+var configs []*config
+
+// config is a validated project config.
+type config struct {
+	filename string
+
+	// dir is a directory that contains the config file.
+	// Path globs are relative to it.
+	dir string
+	// global is set for the config that applies to all files,
+	// even to the files outside of the config directory.
+	global bool
+
+	checks map[string]*checkSettings
+	paths  []*pathSettings
+}
+
+// pathSettings are the checks settings for the files that match the glob.
+type pathSettings struct {
+	glob   *regexp.Regexp
+	checks map[string]*checkSettings
+}
+
+// checkSettings override the registered check defaults.
+type checkSettings struct {
+	// enabled is nil if it's not overridden.
+	enabled *bool
+	// severity is 0 if it's not overridden.
+	severity int
+	// params are validated check params values.
+	// Values are either ints or string slices.
+	params map[string]interface{}
+}
+
+// fileSettings are the effective checks settings for one file.
+// Nil settings are valid and mean that no defaults are overridden.
+type fileSettings map[string]*checkSettings
+
+// enabled reports whether the check is enabled.
+func (s fileSettings) enabled(checkName string) bool {
+	if c := s[checkName]; c != nil && c.enabled != nil {
+		return *c.enabled
+	}
+	return true
+}
+
+// severity returns the check report level.
+func (s fileSettings) severity(checkName string) int {
+	if c := s[checkName]; c != nil && c.severity != 0 {
+		return c.severity
+	}
+	return checksByName[checkName].Severity
+}
+
+// intParam returns the check int param value or def if it's not set.
+func (s fileSettings) intParam(checkName, param string, def int) int {
+	if v, ok := s[checkName].param(param); ok {
+		return v.(int)
+	}
+	return def
+}
+
+// stringsParam returns the check strings param value or def if it's not set.
+func (s fileSettings) stringsParam(checkName, param string, def []string) []string {
+	if v, ok := s[checkName].param(param); ok {
+		return v.([]string)
+	}
+	return def
+}
+
+func (c *checkSettings) param(name string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	v, ok := c.params[name]
+	return v, ok
+}
+
+// settingsFor returns the settings for the analyzed file.
+func settingsFor(filename string) fileSettings {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil
+	}
+	var best *config
+	for _, cfg := range configs {
+		if !cfg.global && !isParentDir(cfg.dir, abs) {
+			continue
+		}
+		if best == nil || len(cfg.dir) > len(best.dir) {
+			best = cfg
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.settingsFor(abs)
+}
+
+// settingsFor merges the global config settings with
+// all matching path settings in their declaration order.
+func (cfg *config) settingsFor(abs string) fileSettings {
+	settings := fileSettings{}
+	merge := func(checks map[string]*checkSettings) {
+		for name, c := range checks {
+			merged := &checkSettings{params: map[string]interface{}{}}
+			if prev := settings[name]; prev != nil {
+				*merged = *prev
+				merged.params = map[string]interface{}{}
+				for k, v := range prev.params {
+					merged.params[k] = v
+				}
+			}
+			if c.enabled != nil {
+				merged.enabled = c.enabled
+			}
+			if c.severity != 0 {
+				merged.severity = c.severity
+			}
+			for k, v := range c.params {
+				merged.params[k] = v
+			}
+			settings[name] = merged
+		}
+	}
+
+	merge(cfg.checks)
+	rel, err := filepath.Rel(cfg.dir, abs)
+	if err != nil {
+		return settings
+	}
+	rel = filepath.ToSlash(rel)
+	for _, p := range cfg.paths {
+		if p.glob.MatchString(rel) {
+			merge(p.checks)
+		}
+	}
+	return settings
+}
+
+func isParentDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// loadConfigs loads the project configs.
+//
+// If filename is not empty, the config is loaded from it and
+// applied to all analyzed files. Otherwise configs are
+// discovered upward from every analyzed path.
+func loadConfigs(filename string, paths []string) error {
+	configs = nil
+	if filename != "" {
+		cfg, err := loadConfig(filename)
+		if err != nil {
+			return err
+		}
+		cfg.global = true
+		configs = append(configs, cfg)
+		return nil
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	loaded := map[string]bool{}
+	for _, path := range paths {
+		filename, err := findConfig(path)
+		if err != nil {
+			return err
+		}
+		if filename == "" || loaded[filename] {
+			continue
+		}
+		loaded[filename] = true
+		cfg, err := loadConfig(filename)
+		if err != nil {
+			return err
+		}
+		configs = append(configs, cfg)
+	}
+	return nil
+}
+
+// findConfig returns the config filename from the path directory
+// or from its nearest parent directory.
+// Empty filename is returned if there is no config.
+func findConfig(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		for _, name := range configFilenames {
+			filename := filepath.Join(dir, name)
+			if _, err := os.Stat(filename); err == nil {
+				return filename, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadConfig reads and validates the config file.
+func loadConfig(filename string) (*config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(data, filepath.Ext(filename) == ".json", filepath.Dir(abs))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	cfg.filename = filename
+	return cfg, nil
+}
+
+// rawConfig is a config file structure.
+type rawConfig struct {
+	Checks map[string]rawCheckConfig `yaml:"checks" json:"checks"`
+	Paths  []rawPathConfig           `yaml:"paths" json:"paths"`
+}
+
+type rawCheckConfig struct {
+	Enabled  *bool                  `yaml:"enabled" json:"enabled"`
+	Severity string                 `yaml:"severity" json:"severity"`
+	Params   map[string]interface{} `yaml:"params" json:"params"`
+}
+
+type rawPathConfig struct {
+	Glob   string                    `yaml:"glob" json:"glob"`
+	Checks map[string]rawCheckConfig `yaml:"checks" json:"checks"`
+}
+
+// parseConfig parses and validates YAML or JSON config data.
+// Path globs are relative to the dir.
+func parseConfig(data []byte, isJSON bool, dir string) (*config, error) {
+	var raw rawConfig
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &config{dir: dir}
+	var err error
+	cfg.checks, err = newChecksSettings("checks", raw.Checks)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range raw.Paths {
+		where := fmt.Sprintf("paths[%d]", i)
+		if p.Glob == "" {
+			return nil, fmt.Errorf("%s: glob is not set", where)
+		}
+		glob, err := compileGlob(p.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", where, err)
+		}
+		checks, err := newChecksSettings(where+".checks", p.Checks)
+		if err != nil {
+			return nil, err
+		}
+		cfg.paths = append(cfg.paths, &pathSettings{glob: glob, checks: checks})
+	}
+	return cfg, nil
+}
+
+func newChecksSettings(where string, raw map[string]rawCheckConfig) (map[string]*checkSettings, error) {
+	// Validate checks in a stable order to get stable error messages.
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make(map[string]*checkSettings, len(raw))
+	for _, name := range names {
+		c, err := newCheckSettings(name, raw[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", where, name, err)
+		}
+		checks[name] = c
+	}
+	return checks, nil
+}
+
+func newCheckSettings(name string, raw rawCheckConfig) (*checkSettings, error) {
+	info, ok := checksByName[name]
+	if !ok {
+		return nil, errors.New("unknown check")
+	}
+
+	c := &checkSettings{enabled: raw.Enabled, params: map[string]interface{}{}}
+	if raw.Severity != "" {
+		c.severity = severityByName(raw.Severity)
+		if c.severity == 0 {
+			return nil, fmt.Errorf("severity: unknown severity %q (expected %s)",
+				raw.Severity, strings.Join(severityList(), ", "))
+		}
+	}
+	for param, v := range raw.Params {
+		p := info.param(param)
+		if p == nil {
+			return nil, fmt.Errorf("params: unknown param %q", param)
+		}
+		converted, err := p.convert(v)
+		if err != nil {
+			return nil, fmt.Errorf("params.%s: %v", param, err)
+		}
+		c.params[param] = converted
+	}
+	return c, nil
+}
+
+// convert converts a decoded config value to the param kind.
+func (p *checkParam) convert(v interface{}) (interface{}, error) {
+	switch p.Kind {
+	case paramInt:
+		switch v := v.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < math.MaxInt32 {
+				return int(v), nil
+			}
+		}
+		return nil, fmt.Errorf("expected an integer, got %v", v)
+	case paramStrings:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings, got %v", v)
+		}
+		strs := make([]string, len(list))
+		for i, x := range list {
+			s, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %v element", x)
+			}
+			strs[i] = s
+		}
+		return strs, nil
+	}
+	panic(fmt.Sprintf("unexpected param kind %q", p.Kind))
+}
+
+// compileGlob converts the slash-separated path glob to the regexp.
+//
+// "*" matches any sequence of non-separator characters,
+// "**" matches any sequence of characters, including separators,
+// "?" matches any single non-separator character.
+// Globs also match all files inside the matched directories,
+// so "vendor" is the same as "vendor/**".
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(glob, "/") {
+		return nil, fmt.Errorf("glob %q: expected a path relative to the config directory", glob)
+	}
+	glob = strings.TrimPrefix(glob, "./")
+	glob = strings.TrimSuffix(glob, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		case glob[i] == '[' || glob[i] == '{':
+			return nil, fmt.Errorf("glob %q: character classes and alternatives are not supported", glob)
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VKCOM/noverify/src/linter"
+)
+
+func TestParseConfig(t *testing.T) {
+	yamlConfig := `
+checks:
+  dupCode:
+    enabled: false
+  badCond:
+    severity: error
+  dupArg:
+    params:
+      functions: [strcmp, array_merge]
+paths:
+  - glob: legacy
+    checks:
+      badCond: {enabled: false}
+  - glob: "**/tests/*.php"
+    checks:
+      dupCode:
+        enabled: true
+        params: {minSize: 10}
+`
+	jsonConfig := `{
+		"checks": {
+			"dupCode": {"enabled": false},
+			"badCond": {"severity": "error"},
+			"dupArg": {"params": {"functions": ["strcmp", "array_merge"]}}
+		},
+		"paths": [
+			{"glob": "legacy", "checks": {"badCond": {"enabled": false}}},
+			{"glob": "**/tests/*.php", "checks": {"dupCode": {"enabled": true, "params": {"minSize": 10}}}}
+		]
+	}`
+
+	for _, test := range []struct {
+		data   string
+		isJSON bool
+	}{
+		{yamlConfig, false},
+		{jsonConfig, true},
+	} {
+		dir := filepath.FromSlash("/project")
+		cfg, err := parseConfig([]byte(test.data), test.isJSON, dir)
+		if err != nil {
+			t.Fatalf("parse config: %v", err)
+		}
+
+		settings := cfg.settingsFor(filepath.Join(dir, "src", "a.php"))
+		if settings.enabled("dupCode") || !settings.enabled("badCond") || !settings.enabled("dupSubExpr") {
+			t.Errorf("src: unexpected enabled checks")
+		}
+		if settings.severity("badCond") != linter.LevelError || settings.severity("dupArg") != linter.LevelWarning {
+			t.Errorf("src: unexpected severities")
+		}
+		funcs := settings.stringsParam("dupArg", "functions", nil)
+		if !reflect.DeepEqual(funcs, []string{"strcmp", "array_merge"}) {
+			t.Errorf("src: unexpected dupArg functions: %v", funcs)
+		}
+
+		settings = cfg.settingsFor(filepath.Join(dir, "legacy", "b.php"))
+		if settings.enabled("badCond") || settings.severity("badCond") != linter.LevelError {
+			t.Errorf("legacy: badCond settings are not merged")
+		}
+
+		settings = cfg.settingsFor(filepath.Join(dir, "lib", "tests", "c.php"))
+		if !settings.enabled("dupCode") || settings.intParam("dupCode", "minSize", 40) != 10 {
+			t.Errorf("tests: dupCode settings are not merged")
+		}
+		if settings.intParam("dupCode", "minGroup", 2) != 2 {
+			t.Errorf("tests: unexpected minGroup default")
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{`checks: {fooBar: {enabled: false}}`, `checks.fooBar: unknown check`},
+		{`checks: {badCond: {severity: fatal}}`, `checks.badCond: severity: unknown severity "fatal" (expected error, warning, info, hint, maybe)`},
+		{`checks: {badCond: {params: {x: 1}}}`, `checks.badCond: params: unknown param "x"`},
+		{`checks: {dupCode: {params: {minSize: big}}}`, `checks.dupCode: params.minSize: expected an integer, got big`},
+		{`checks: {dupArg: {params: {functions: strcmp}}}`, `checks.dupArg: params.functions: expected a list of strings, got strcmp`},
+		{`checks: {badCond: {enabeld: false}}`, `field enabeld not found`},
+		{`paths: [{checks: {}}]`, `paths[0]: glob is not set`},
+		{`paths: [{glob: /abs}]`, `paths[0]: glob "/abs": expected a path relative to the config directory`},
+		{`paths: [{glob: a, checks: {badCond: {severity: x}}}]`, `paths[0].checks.badCond: severity: unknown severity "x"`},
+		{`checks: [1, 2]`, `cannot unmarshal`},
+	}
+
+	for _, test := range tests {
+		_, err := parseConfig([]byte(test.data), false, "/project")
+		if err == nil {
+			t.Errorf("%s: expected an error", test.data)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %q doesn't contain %q", test.data, err, test.err)
+		}
+	}
+
+	_, err := parseConfig([]byte(`{"check": {}}`), true, "/project")
+	if err == nil || !strings.Contains(err.Error(), `unknown field "check"`) {
+		t.Errorf("unexpected JSON error: %v", err)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{`legacy`, `legacy/a.php`, true},
+		{`legacy/`, `legacy/sub/a.php`, true},
+		{`legacy`, `legacy2/a.php`, false},
+		{`legacy`, `src/legacy/a.php`, false},
+		{`./src/*.php`, `src/a.php`, true},
+		{`src/*.php`, `src/sub/a.php`, false},
+		{`src/**/*.php`, `src/a.php`, true},
+		{`src/**/*.php`, `src/sub/dir/a.php`, true},
+		{`**/tests`, `a/b/tests/x.php`, true},
+		{`**/tests`, `tests/x.php`, true},
+		{`src/?.php`, `src/a.php`, true},
+		{`src/?.php`, `src/ab.php`, false},
+		{`a.b`, `axb`, false},
+	}
+
+	for _, test := range tests {
+		re, err := compileGlob(test.glob)
+		if err != nil {
+			t.Errorf("compile %q: %v", test.glob, err)
+			continue
+		}
+		if have := re.MatchString(test.path); have != test.match {
+			t.Errorf("glob %q match %q: have %v, want %v", test.glob, test.path, have, test.match)
+		}
+	}
+
+	if _, err := compileGlob(`src/{a,b}`); err == nil {
+		t.Errorf("expected an error for unsupported glob syntax")
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "php-critic-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "project", "src", "pkg")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(sub, "a.php")
+	configFile := filepath.Join(root, "project", ".php-critic.yml")
+	for _, filename := range []string{file, configFile} {
+		if err := ioutil.WriteFile(filename, []byte("checks: {dupCode: {enabled: false}}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{sub, file, filepath.Join(root, "project")} {
+		have, err := findConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have != configFile {
+			t.Errorf("find config for %s: have %q, want %q", path, have, configFile)
+		}
+	}
+
+	defer func() { configs = nil }()
+	if err := loadConfigs("", []string{file, sub}); err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("expected 1 config, got %d", len(configs))
+	}
+	if settingsFor(file).enabled("dupCode") {
+		t.Errorf("dupCode is expected to be disabled for %s", file)
+	}
+	if !settingsFor(filepath.Join(root, "other.php")).enabled("dupCode") {
+		t.Errorf("config is not expected to be applied outside of its directory")
+	}
+}
@@ -0,0 +1,904 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/VKCOM/noverify/src/meta"
+	"github.com/quasilyte/php-critic/internal/constant"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/assign"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/walker"
+)
+
+// localConsts maps local variable read expressions to their known values.
+type localConsts map[*expr.Variable]constant.Value
+
+// constEnv maps local variable names to their known values
+// at some point of the function execution.
+//
+// nil env describes unreachable code.
+type constEnv map[string]constant.Value
+
+func (env constEnv) clone() constEnv {
+	if env == nil {
+		return nil
+	}
+	cloned := make(constEnv, len(env))
+	for k, v := range env {
+		cloned[k] = v
+	}
+	return cloned
+}
+
+// meet returns an env that holds only the facts that are true for both x and y.
+func (env constEnv) meet(other constEnv) constEnv {
+	switch {
+	case env == nil:
+		return other
+	case other == nil:
+		return env
+	}
+	res := constEnv{}
+	for k, v := range env {
+		w, ok := other[k]
+		if ok && constant.Identical(v, w) == constant.BoolValue(true) {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// propagateConsts computes the values of the local variables
+// that are known at their read locations inside fn body.
+//
+// Facts are written to the locals as soon as they're discovered,
+// so constFold can use them while the propagation is in progress.
+func propagateConsts(mi *metainfoExt, fn node.Node, locals localConsts) {
+	p := newConstPropagator(mi, locals)
+	switch fn := fn.(type) {
+	case *stmt.Function:
+		p.bindParams(fn.Params)
+		p.walkList(fn.Stmts)
+	case *stmt.ClassMethod:
+		p.bindParams(fn.Params)
+		p.walkList(fn.Stmts)
+	}
+	if p.untracked {
+		p.forget()
+	}
+}
+
+// constPropagator implements a flow-sensitive constant propagation
+// of the local variables inside a single function body.
+//
+// Variables that are assigned inside loops are not tracked there.
+// Variables that can be changed behind our back (references,
+// globals, static vars) are never tracked after they're bound.
+type constPropagator struct {
+	mi *metainfoExt
+
+	facts    localConsts
+	recorded []*expr.Variable
+
+	env constEnv
+
+	// escaped holds the names of variables that are not tracked anymore.
+	escaped map[string]bool
+
+	// conditional is a depth of the conditionally evaluated expressions,
+	// like the "&&" right operand. Assignments there only kill facts.
+	conditional int
+
+	// untracked is set when the function can change any of its variables,
+	// like with extract() or $$name assignment.
+	untracked bool
+}
+
+func newConstPropagator(mi *metainfoExt, facts localConsts) *constPropagator {
+	return &constPropagator{
+		mi:      mi,
+		facts:   facts,
+		env:     constEnv{},
+		escaped: map[string]bool{},
+	}
+}
+
+func (p *constPropagator) GetChildrenVisitor(key string) walker.Visitor { return p }
+func (p *constPropagator) LeaveNode(w walker.Walkable)                  {}
+
+func (p *constPropagator) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *stmt.Class, *stmt.Interface, *stmt.Trait:
+		// Nested declarations are analyzed separately.
+		return false
+	case *stmt.Goto:
+		// Jumps are not followed.
+		p.untracked = true
+		return false
+
+	case *stmt.Return:
+		p.walk(n.Expr)
+		p.env = nil
+		return false
+	case *stmt.Throw:
+		p.walk(n.Expr)
+		p.env = nil
+		return false
+	case *stmt.Break, *stmt.Continue:
+		p.env = nil
+		return false
+
+	case *stmt.If:
+		p.walkIf(n.Cond, n.Stmt, n.ElseIf, n.Else)
+		return false
+	case *stmt.AltIf:
+		p.walkIf(n.Cond, n.Stmt, n.ElseIf, n.Else)
+		return false
+	case *stmt.While:
+		p.walkWhile(n, n.Cond, n.Stmt)
+		return false
+	case *stmt.AltWhile:
+		p.walkWhile(n, n.Cond, n.Stmt)
+		return false
+	case *stmt.Do:
+		entry := p.enterLoop(n)
+		p.walk(n.Stmt)
+		p.walk(n.Cond)
+		p.env = entry
+		return false
+	case *stmt.For:
+		p.walkFor(n.Init, n.Cond, n.Loop, n.Stmt)
+		return false
+	case *stmt.AltFor:
+		p.walkFor(n.Init, n.Cond, n.Loop, n.Stmt)
+		return false
+	case *stmt.Foreach:
+		p.walkForeach(n.Expr, n.Key, n.Variable, n.Stmt, n.ByRef)
+		return false
+	case *stmt.AltForeach:
+		p.walkForeach(n.Expr, n.Key, n.Variable, n.Stmt, n.ByRef)
+		return false
+	case *stmt.Switch:
+		p.walkSwitch(n.Cond, n.Cases)
+		return false
+	case *stmt.AltSwitch:
+		p.walkSwitch(n.Cond, n.Cases)
+		return false
+	case *stmt.Try:
+		p.walkTry(n)
+		return false
+
+	case *stmt.Global:
+		for _, v := range n.Vars {
+			p.escape(v)
+		}
+		return false
+	case *stmt.Static:
+		for _, v := range n.Vars {
+			v := v.(*stmt.StaticVar)
+			p.walk(v.Expr)
+			p.escape(v.Variable)
+		}
+		return false
+	case *stmt.Unset:
+		for _, v := range n.Vars {
+			p.assign(v, constant.UnknownValue{})
+		}
+		return false
+
+	case *expr.Variable:
+		name, ok := localVarName(n)
+		if !ok {
+			return true
+		}
+		if v, ok := p.lookup(name); ok {
+			p.facts[n] = v
+			p.recorded = append(p.recorded, n)
+		}
+		return false
+	case *expr.StaticPropertyFetch:
+		// A::$x property is not a local variable.
+		p.walk(n.Class)
+		if v, ok := n.Property.(*expr.Variable); ok {
+			if _, ok := v.VarName.(*node.Identifier); !ok {
+				p.walk(v.VarName)
+			}
+		}
+		return false
+
+	case *expr.ArrayItem:
+		if n.ByRef {
+			// [&$x] makes $x modifiable through the array.
+			p.escape(n.Val)
+		}
+		return true
+
+	case *assign.Assign:
+		p.walk(n.Expression)
+		p.assign(n.Variable, constFold(p.mi, n.Expression))
+		return false
+	case *assign.Reference:
+		p.walk(n.Expression)
+		p.escape(n.Expression)
+		p.escape(n.Variable)
+		return false
+	case *assign.Plus:
+		p.compoundAssign(n.Variable, n.Expression, constant.Add)
+		return false
+	case *assign.Minus:
+		p.compoundAssign(n.Variable, n.Expression, constant.Sub)
+		return false
+	case *assign.Mul:
+		p.compoundAssign(n.Variable, n.Expression, constant.Mul)
+		return false
+	case *assign.Div:
+		p.compoundAssign(n.Variable, n.Expression, constant.Div)
+		return false
+	case *assign.Mod:
+		p.compoundAssign(n.Variable, n.Expression, constant.Mod)
+		return false
+	case *assign.Pow:
+		p.compoundAssign(n.Variable, n.Expression, constant.Pow)
+		return false
+	case *assign.Concat:
+		p.compoundAssign(n.Variable, n.Expression, constant.Concat)
+		return false
+	case *assign.BitwiseAnd:
+		p.compoundAssign(n.Variable, n.Expression, constant.BitAnd)
+		return false
+	case *assign.BitwiseOr:
+		p.compoundAssign(n.Variable, n.Expression, constant.BitOr)
+		return false
+	case *assign.BitwiseXor:
+		p.compoundAssign(n.Variable, n.Expression, constant.BitXor)
+		return false
+	case *assign.ShiftLeft:
+		p.compoundAssign(n.Variable, n.Expression, constant.ShiftLeft)
+		return false
+	case *assign.ShiftRight:
+		p.compoundAssign(n.Variable, n.Expression, constant.ShiftRight)
+		return false
+	case *expr.PreInc:
+		p.incDec(n.Variable, constant.Add)
+		return false
+	case *expr.PostInc:
+		p.incDec(n.Variable, constant.Add)
+		return false
+	case *expr.PreDec:
+		p.incDec(n.Variable, constant.Sub)
+		return false
+	case *expr.PostDec:
+		p.incDec(n.Variable, constant.Sub)
+		return false
+
+	case *binary.BooleanAnd:
+		p.walkConditional(n.Left, n.Right)
+		return false
+	case *binary.BooleanOr:
+		p.walkConditional(n.Left, n.Right)
+		return false
+	case *binary.LogicalAnd:
+		p.walkConditional(n.Left, n.Right)
+		return false
+	case *binary.LogicalOr:
+		p.walkConditional(n.Left, n.Right)
+		return false
+	case *binary.Coalesce:
+		p.walkConditional(n.Left, n.Right)
+		return false
+	case *expr.Ternary:
+		p.walkConditional(n.Condition, n.IfTrue, n.IfFalse)
+		return false
+
+	case *expr.FunctionCall:
+		p.walk(n.Function)
+		p.walkList(n.Arguments)
+		p.handleFunctionCall(n)
+		return false
+	case *expr.MethodCall:
+		p.walk(n.Variable)
+		p.walk(n.Method)
+		p.walkList(n.Arguments)
+		p.escapeArgs(n.Arguments)
+		return false
+	case *expr.StaticCall:
+		p.walk(n.Class)
+		p.walk(n.Call)
+		p.walkList(n.Arguments)
+		p.escapeArgs(n.Arguments)
+		return false
+	case *expr.New:
+		if _, ok := n.Class.(*stmt.Class); !ok {
+			p.walk(n.Class)
+		}
+		p.walkList(n.Arguments)
+		p.escapeArgs(n.Arguments)
+		return false
+	case *expr.Closure:
+		p.walkClosure(n)
+		return false
+
+	case *expr.Include, *expr.IncludeOnce, *expr.Require, *expr.RequireOnce, *expr.Eval:
+		// Included code shares the local variables scope.
+		p.untracked = true
+		return false
+	}
+
+	return true
+}
+
+func (p *constPropagator) walk(n node.Node) {
+	if n != nil {
+		n.Walk(p)
+	}
+}
+
+func (p *constPropagator) walkList(list []node.Node) {
+	for _, n := range list {
+		p.walk(n)
+	}
+}
+
+// forget removes all facts that were recorded by p.
+func (p *constPropagator) forget() {
+	for _, v := range p.recorded {
+		delete(p.facts, v)
+	}
+	p.recorded = nil
+}
+
+func (p *constPropagator) bindParams(params []node.Node) {
+	for _, param := range params {
+		param := param.(*node.Parameter)
+		if param.ByRef {
+			p.escape(param.Variable)
+		}
+	}
+}
+
+func (p *constPropagator) lookup(name string) (constant.Value, bool) {
+	if p.escaped[name] || !isTrackedVar(name) {
+		return nil, false
+	}
+	v, ok := p.env[name]
+	return v, ok
+}
+
+func (p *constPropagator) setVar(name string, v constant.Value) {
+	if p.env == nil {
+		return
+	}
+	_, unknown := v.(constant.UnknownValue)
+	if unknown || p.conditional != 0 || p.escaped[name] || !isTrackedVar(name) {
+		delete(p.env, name)
+		return
+	}
+	p.env[name] = v
+}
+
+// assign binds v value to the assignment target.
+func (p *constPropagator) assign(target node.Node, v constant.Value) {
+	switch target := target.(type) {
+	case nil:
+		return
+	case *expr.Variable:
+		name, ok := localVarName(target)
+		if !ok {
+			p.walk(target.VarName)
+			p.untracked = true
+			return
+		}
+		p.setVar(name, v)
+	case *expr.List:
+		p.assignList(target.Items)
+	case *expr.ShortList:
+		p.assignList(target.Items)
+	case *expr.Array:
+		// Nested list().
+		p.assignList(target.Items)
+	case *expr.ShortArray:
+		// Nested [] list.
+		p.assignList(target.Items)
+	default:
+		// Element or property assignment, like "$a[$k] = $v".
+		p.walk(target)
+		p.kill(target)
+	}
+}
+
+func (p *constPropagator) assignList(items []node.Node) {
+	for _, item := range items {
+		item, ok := item.(*expr.ArrayItem)
+		if !ok {
+			continue
+		}
+		p.walk(item.Key)
+		if item.ByRef {
+			p.escape(item.Val)
+		} else {
+			p.assign(item.Val, constant.UnknownValue{})
+		}
+	}
+}
+
+func (p *constPropagator) compoundAssign(target, e node.Node, op func(x, y constant.Value) constant.Value) {
+	p.walk(e)
+	v, ok := target.(*expr.Variable)
+	if !ok {
+		p.assign(target, constant.UnknownValue{})
+		return
+	}
+	name, ok := localVarName(v)
+	if !ok {
+		p.assign(target, constant.UnknownValue{})
+		return
+	}
+	old, ok := p.lookup(name)
+	if !ok {
+		p.setVar(name, constant.UnknownValue{})
+		return
+	}
+	p.setVar(name, op(old, constFold(p.mi, e)))
+}
+
+// incDec handles ++ and -- operators.
+// Only numbers are tracked, since strings are incremented in a special way.
+func (p *constPropagator) incDec(target node.Node, op func(x, y constant.Value) constant.Value) {
+	v, ok := target.(*expr.Variable)
+	if !ok {
+		p.assign(target, constant.UnknownValue{})
+		return
+	}
+	name, ok := localVarName(v)
+	if !ok {
+		p.assign(target, constant.UnknownValue{})
+		return
+	}
+	old, _ := p.lookup(name)
+	switch old.(type) {
+	case constant.IntValue, constant.FloatValue:
+		p.setVar(name, op(old, constant.IntValue(1)))
+	default:
+		p.setVar(name, constant.UnknownValue{})
+	}
+}
+
+// kill removes the facts about the variable that is modified by the n write.
+func (p *constPropagator) kill(n node.Node) {
+	v := baseVar(n)
+	if v == nil {
+		return
+	}
+	name, ok := localVarName(v)
+	if !ok {
+		p.untracked = true
+		return
+	}
+	if p.env != nil {
+		delete(p.env, name)
+	}
+}
+
+// escape stops tracking the variable that is bound to n reference.
+func (p *constPropagator) escape(n node.Node) {
+	if list := listItems(n); list != nil {
+		for _, item := range list {
+			if item, ok := item.(*expr.ArrayItem); ok {
+				p.escape(item.Val)
+			}
+		}
+		return
+	}
+	v := baseVar(n)
+	if v == nil {
+		return
+	}
+	name, ok := localVarName(v)
+	if !ok {
+		p.untracked = true
+		return
+	}
+	p.escaped[name] = true
+	if p.env != nil {
+		delete(p.env, name)
+	}
+}
+
+func (p *constPropagator) escapeArgs(args []node.Node) {
+	for _, arg := range args {
+		if arg, ok := arg.(*node.Argument); ok {
+			p.escape(arg.Expr)
+		}
+	}
+}
+
+func (p *constPropagator) handleFunctionCall(call *expr.FunctionCall) {
+	fqn, ok := funcCallName(p.mi.classParseState(), call)
+	if !ok {
+		p.escapeArgs(call.Arguments)
+		return
+	}
+	switch strings.ToLower(fqn) {
+	case `\extract`:
+		p.untracked = true
+		return
+	case `\parse_str`:
+		if len(call.Arguments) == 1 {
+			p.untracked = true
+			return
+		}
+	}
+	fn, ok := meta.Info.GetFunction(fqn)
+	if !ok {
+		if _, ok := constant.LookupFunc(fqn); ok {
+			// Pure functions don't modify their arguments.
+			return
+		}
+		p.escapeArgs(call.Arguments)
+		return
+	}
+	for i, arg := range call.Arguments {
+		arg, ok := arg.(*node.Argument)
+		if !ok {
+			continue
+		}
+		byRef := arg.IsReference
+		switch {
+		case i < len(fn.Params):
+			byRef = byRef || fn.Params[i].IsRef
+		case len(fn.Params) != 0:
+			// Variadic by-ref param.
+			byRef = byRef || fn.Params[len(fn.Params)-1].IsRef
+		}
+		if byRef {
+			p.escape(arg.Expr)
+		}
+	}
+}
+
+// walkConditional walks the first node unconditionally
+// and the rest of them as conditionally evaluated expressions.
+func (p *constPropagator) walkConditional(first node.Node, rest ...node.Node) {
+	p.walk(first)
+	p.conditional++
+	for _, n := range rest {
+		p.walk(n)
+	}
+	p.conditional--
+}
+
+func (p *constPropagator) walkIf(cond, body node.Node, elseifList []node.Node, elseNode node.Node) {
+	p.walk(cond)
+	fallthroughEnv := p.env
+	p.env = fallthroughEnv.clone()
+	p.walk(body)
+	res := p.env
+
+	for _, elseif := range elseifList {
+		p.env = fallthroughEnv
+		switch elseif := elseif.(type) {
+		case *stmt.ElseIf:
+			p.walk(elseif.Cond)
+			fallthroughEnv = p.env
+			p.env = fallthroughEnv.clone()
+			p.walk(elseif.Stmt)
+		case *stmt.AltElseIf:
+			p.walk(elseif.Cond)
+			fallthroughEnv = p.env
+			p.env = fallthroughEnv.clone()
+			p.walk(elseif.Stmt)
+		}
+		res = res.meet(p.env)
+	}
+
+	p.env = fallthroughEnv
+	switch elseNode := elseNode.(type) {
+	case *stmt.Else:
+		p.walk(elseNode.Stmt)
+	case *stmt.AltElse:
+		p.walk(elseNode.Stmt)
+	}
+	p.env = res.meet(p.env)
+}
+
+// enterLoop kills the facts about the variables that are assigned
+// inside the loop parts, so they're valid for any loop iteration.
+//
+// Returns the env that is valid after the loop.
+func (p *constPropagator) enterLoop(parts ...node.Node) constEnv {
+	c := assignedVarsCollector{vars: map[string]bool{}}
+	for _, n := range parts {
+		if n != nil {
+			n.Walk(&c)
+		}
+	}
+	if c.all {
+		p.untracked = true
+	}
+	for name := range c.vars {
+		delete(p.env, name)
+	}
+	return p.env.clone()
+}
+
+func (p *constPropagator) walkWhile(loop, cond, body node.Node) {
+	entry := p.enterLoop(loop)
+	p.walk(cond)
+	p.walk(body)
+	p.env = entry
+}
+
+func (p *constPropagator) walkFor(init, cond, loop []node.Node, body node.Node) {
+	p.walkList(init)
+	parts := append(append([]node.Node{body}, cond...), loop...)
+	entry := p.enterLoop(parts...)
+	p.walkList(cond)
+	p.walk(body)
+	p.walkList(loop)
+	p.env = entry
+}
+
+func (p *constPropagator) walkForeach(e, key, value, body node.Node, byRef bool) {
+	p.walk(e)
+	entry := p.enterLoop(key, value, body)
+	p.assign(key, constant.UnknownValue{})
+	if byRef {
+		p.escape(value)
+	} else {
+		p.assign(value, constant.UnknownValue{})
+	}
+	p.walk(body)
+	p.env = entry
+}
+
+func (p *constPropagator) walkSwitch(cond node.Node, cases []node.Node) {
+	p.walk(cond)
+	entry := p.enterLoop(cases...)
+	for _, c := range cases {
+		p.env = entry.clone()
+		switch c := c.(type) {
+		case *stmt.Case:
+			p.walk(c.Cond)
+			p.walkList(c.Stmts)
+		case *stmt.Default:
+			p.walkList(c.Stmts)
+		}
+	}
+	p.env = entry
+}
+
+// walkTry handles try statement.
+// Exceptions can be thrown at any point, so every variable
+// that is assigned somewhere inside the statement is killed.
+func (p *constPropagator) walkTry(n *stmt.Try) {
+	entry := p.enterLoop(n)
+	p.walkList(n.Stmts)
+	for _, c := range n.Catches {
+		c := c.(*stmt.Catch)
+		p.env = entry.clone()
+		p.assign(c.Variable, constant.UnknownValue{})
+		p.walkList(c.Stmts)
+	}
+	if finally, ok := n.Finally.(*stmt.Finally); ok {
+		p.env = entry.clone()
+		p.walkList(finally.Stmts)
+	}
+	p.env = entry
+}
+
+// walkClosure analyzes closure body with its own variables scope.
+func (p *constPropagator) walkClosure(closure *expr.Closure) {
+	inner := newConstPropagator(p.mi, p.facts)
+	for _, u := range closure.Uses {
+		u := u.(*expr.ClosureUse)
+		if u.ByRef {
+			p.escape(u.Variable)
+			inner.escape(u.Variable)
+			continue
+		}
+		name, ok := localVarName(u.Variable.(*expr.Variable))
+		if !ok {
+			continue
+		}
+		if v, ok := p.lookup(name); ok {
+			inner.env[name] = v
+		}
+	}
+	inner.bindParams(closure.Params)
+	inner.walkList(closure.Stmts)
+	if inner.untracked {
+		inner.forget()
+		return
+	}
+	p.recorded = append(p.recorded, inner.recorded...)
+}
+
+// assignedVarsCollector finds all variables that can be
+// modified during the visited code execution.
+type assignedVarsCollector struct {
+	vars map[string]bool
+
+	// all is set if any variable can be modified.
+	all bool
+}
+
+func (c *assignedVarsCollector) GetChildrenVisitor(key string) walker.Visitor { return c }
+func (c *assignedVarsCollector) LeaveNode(w walker.Walkable)                  {}
+
+func (c *assignedVarsCollector) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *stmt.Class, *stmt.Interface, *stmt.Trait:
+		return false
+	case *expr.Closure:
+		for _, u := range n.Uses {
+			if u := u.(*expr.ClosureUse); u.ByRef {
+				c.add(u.Variable)
+			}
+		}
+		return false
+
+	case *assign.Assign:
+		c.add(n.Variable)
+	case *assign.Reference:
+		c.add(n.Variable)
+		c.add(n.Expression)
+	case *assign.Plus:
+		c.add(n.Variable)
+	case *assign.Minus:
+		c.add(n.Variable)
+	case *assign.Mul:
+		c.add(n.Variable)
+	case *assign.Div:
+		c.add(n.Variable)
+	case *assign.Mod:
+		c.add(n.Variable)
+	case *assign.Pow:
+		c.add(n.Variable)
+	case *assign.Concat:
+		c.add(n.Variable)
+	case *assign.BitwiseAnd:
+		c.add(n.Variable)
+	case *assign.BitwiseOr:
+		c.add(n.Variable)
+	case *assign.BitwiseXor:
+		c.add(n.Variable)
+	case *assign.ShiftLeft:
+		c.add(n.Variable)
+	case *assign.ShiftRight:
+		c.add(n.Variable)
+	case *expr.PreInc:
+		c.add(n.Variable)
+	case *expr.PostInc:
+		c.add(n.Variable)
+	case *expr.PreDec:
+		c.add(n.Variable)
+	case *expr.PostDec:
+		c.add(n.Variable)
+
+	case *stmt.Foreach:
+		c.add(n.Key)
+		c.add(n.Variable)
+	case *stmt.AltForeach:
+		c.add(n.Key)
+		c.add(n.Variable)
+	case *stmt.Catch:
+		c.add(n.Variable)
+	case *stmt.Global:
+		c.addList(n.Vars)
+	case *stmt.Static:
+		for _, v := range n.Vars {
+			c.add(v.(*stmt.StaticVar).Variable)
+		}
+	case *stmt.Unset:
+		c.addList(n.Vars)
+
+	case *expr.FunctionCall:
+		c.addArgs(n.Arguments)
+		switch strings.ToLower(meta.NameNodeToString(n.Function)) {
+		case "extract", `\extract`, "parse_str", `\parse_str`:
+			c.all = true
+		}
+	case *expr.MethodCall:
+		c.addArgs(n.Arguments)
+	case *expr.StaticCall:
+		c.addArgs(n.Arguments)
+	case *expr.New:
+		c.addArgs(n.Arguments)
+
+	case *expr.Include, *expr.IncludeOnce, *expr.Require, *expr.RequireOnce, *expr.Eval:
+		c.all = true
+	}
+
+	return true
+}
+
+func (c *assignedVarsCollector) add(n node.Node) {
+	if list := listItems(n); list != nil {
+		for _, item := range list {
+			if item, ok := item.(*expr.ArrayItem); ok {
+				c.add(item.Val)
+			}
+		}
+		return
+	}
+	v := baseVar(n)
+	if v == nil {
+		return
+	}
+	if name, ok := localVarName(v); ok {
+		c.vars[name] = true
+	} else {
+		c.all = true
+	}
+}
+
+func (c *assignedVarsCollector) addList(list []node.Node) {
+	for _, n := range list {
+		c.add(n)
+	}
+}
+
+func (c *assignedVarsCollector) addArgs(args []node.Node) {
+	for _, arg := range args {
+		if arg, ok := arg.(*node.Argument); ok {
+			c.add(arg.Expr)
+		}
+	}
+}
+
+// listItems returns the items of list() assignment target.
+// Returns nil if n is not a list.
+func listItems(n node.Node) []node.Node {
+	switch n := n.(type) {
+	case *expr.List:
+		return n.Items
+	case *expr.ShortList:
+		return n.Items
+	case *expr.Array:
+		return n.Items
+	case *expr.ShortArray:
+		return n.Items
+	}
+	return nil
+}
+
+// baseVar returns a variable that is modified when n is written,
+// like $a for "$a[0]->b" expression.
+// Returns nil if there is no such variable.
+func baseVar(n node.Node) *expr.Variable {
+	for {
+		switch e := n.(type) {
+		case *expr.Variable:
+			return e
+		case *expr.ArrayDimFetch:
+			n = e.Variable
+		case *expr.PropertyFetch:
+			n = e.Variable
+		default:
+			return nil
+		}
+	}
+}
+
+// localVarName returns the name of the local variable v.
+// Returns false for the dynamic variables like $$name.
+func localVarName(v *expr.Variable) (string, bool) {
+	id, ok := v.VarName.(*node.Identifier)
+	if !ok {
+		return "", false
+	}
+	return id.Value, true
+}
+
+// isTrackedVar reports whether a variable with the given name can be tracked.
+// $this and superglobals are never tracked.
+func isTrackedVar(name string) bool {
+	switch name {
+	case "this", "GLOBALS", "_SERVER", "_GET", "_POST", "_FILES", "_COOKIE", "_SESSION", "_REQUEST", "_ENV":
+		return false
+	}
+	return true
+}
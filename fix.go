@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VKCOM/noverify/src/linter"
+	"github.com/VKCOM/noverify/src/meta"
+	"github.com/quasilyte/php-critic/internal/astcmp"
+	"github.com/quasilyte/php-critic/internal/astprint"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/php7"
+)
+
+// textEdit is a machine-applicable fix of a report:
+// the [start, end) bytes range of the file is replaced by the replacement.
+type textEdit struct {
+	filename    string
+	start, end  int
+	replacement string
+
+	// checkName and line identify the fixed report.
+	checkName string
+	line      int
+}
+
+// fixes are the edits of all fixable reports.
+var fixes editSet
+
+// editSet collects the report edits grouped by the filename.
+type editSet struct {
+	mu    sync.Mutex
+	edits map[string][]*textEdit
+}
+
+func (s *editSet) add(e *textEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.edits == nil {
+		s.edits = make(map[string][]*textEdit)
+	}
+	s.edits[e.filename] = append(s.edits[e.filename], e)
+}
+
+// take returns all collected edits and resets the set.
+func (s *editSet) take() map[string][]*textEdit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	edits := s.edits
+	s.edits = nil
+	return edits
+}
+
+// reportFix is like report, but also attaches an edit
+// that replaces the old node with the fix node.
+//
+// The report is still issued if the fix is nil or can't be printed,
+// it's just not fixable.
+func reportFix(r reporter, info *fileInfo, n, old, fix node.Node, checkName, format string, args ...interface{}) {
+	if !report(r, info, n, checkName, format, args...) || info == nil || fix == nil {
+		return
+	}
+	pos := info.nodePositions()[old]
+	if pos == nil {
+		return
+	}
+	replacement, ok := printFix(fix)
+	if !ok {
+		return
+	}
+	fixes.add(&textEdit{
+		filename:    info.filename,
+		start:       pos.StartPos - 1,
+		end:         pos.EndPos,
+		replacement: replacement,
+		checkName:   checkName,
+		line:        pos.StartLine,
+	})
+}
+
+// printFix prints the n expression as PHP source.
+//
+// As a safety net, the printed code is parsed again and must be equal to n.
+// It only proves that the code is printed correctly: whether
+// the fix keeps the code behavior is up to the check that builds it.
+func printFix(n node.Node) (string, bool) {
+	code := astprint.Print(n)
+
+	p := php7.NewParser(strings.NewReader("<?php "+code+";"), "fix.php")
+	p.Parse()
+	if len(p.GetErrors()) != 0 {
+		return "", false
+	}
+	root, ok := p.GetRootNode().(*stmt.StmtList)
+	if !ok || len(root.Stmts) != 1 {
+		return "", false
+	}
+	e, ok := root.Stmts[0].(*stmt.Expression)
+	if !ok || !astcmp.Equal(e.Expr, n) {
+		return "", false
+	}
+	return code, true
+}
+
+// fixFilter drops the edits of the reports that noverify doesn't print:
+// excluded by the -exclude or -exclude-checks flags or disabled with
+// '@linter disable' in a file that is not matched by -allow-disable.
+type fixFilter struct {
+	exclude       *regexp.Regexp
+	excludeChecks map[string]bool
+	allowDisable  *regexp.Regexp
+
+	// disabledFiles are the files with '@linter disable'.
+	disabledFiles map[string]bool
+}
+
+// newFixFilter creates a filter from the noverify flags.
+func newFixFilter() (*fixFilter, error) {
+	f := &fixFilter{
+		excludeChecks: make(map[string]bool),
+		disabledFiles: make(map[string]bool),
+	}
+	var err error
+	if s := flagValue("exclude"); s != "" {
+		if f.exclude, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("incorrect exclude regex: %v", err)
+		}
+	}
+	if s := flagValue("allow-disable"); s != "" {
+		if f.allowDisable, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("incorrect 'allow disable' regex: %v", err)
+		}
+	}
+	for _, name := range strings.Split(flagValue("exclude-checks"), ",") {
+		f.excludeChecks[strings.TrimSpace(name)] = true
+	}
+	return f, nil
+}
+
+func flagValue(name string) string {
+	if f := flag.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// addReports records the disabled files of the reports.
+func (f *fixFilter) addReports(reports []*linter.Report) {
+	for _, r := range reports {
+		if r.IsDisabledByUser() {
+			f.disabledFiles[r.GetFilename()] = true
+		}
+	}
+}
+
+func (f *fixFilter) allowed(e *textEdit) bool {
+	if f.excludeChecks[e.checkName] {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(e.filename) {
+		return false
+	}
+	if f.disabledFiles[e.filename] {
+		return f.allowDisable != nil && f.allowDisable.MatchString(e.filename)
+	}
+	return true
+}
+
+// filter returns the allowed edits.
+func (f *fixFilter) filter(edits map[string][]*textEdit) map[string][]*textEdit {
+	filtered := make(map[string][]*textEdit, len(edits))
+	for filename, list := range edits {
+		for _, e := range list {
+			if f.allowed(e) {
+				filtered[filename] = append(filtered[filename], e)
+			}
+		}
+	}
+	return filtered
+}
+
+// applyEdits applies the edits to src in their start order.
+// Edit that overlaps an already applied edit is skipped,
+// so it can be applied by the next run.
+//
+// Returns the new contents and the applied edits.
+func applyEdits(src []byte, edits []*textEdit) ([]byte, []*textEdit) {
+	sorted := make([]*textEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].start != sorted[j].start {
+			return sorted[i].start < sorted[j].start
+		}
+		return sorted[i].end < sorted[j].end
+	})
+
+	var out bytes.Buffer
+	var applied []*textEdit
+	offset := 0
+	for _, e := range sorted {
+		if e.start < offset || e.end < e.start || e.end > len(src) {
+			continue
+		}
+		out.Write(src[offset:e.start])
+		out.WriteString(e.replacement)
+		offset = e.end
+		applied = append(applied, e)
+	}
+	out.Write(src[offset:])
+	return out.Bytes(), applied
+}
+
+// diffContext is a number of unchanged lines around the diff hunks.
+const diffContext = 3
+
+// unifiedDiff returns a unified diff between src and src with
+// the edits applied. The edits must be sorted and non-overlapping,
+// as returned by the applyEdits.
+func unifiedDiff(filename string, src []byte, edits []*textEdit) string {
+	lines := splitLines(src)
+	starts := make([]int, len(lines)+1)
+	for i, l := range lines {
+		starts[i+1] = starts[i] + len(l)
+	}
+	lineOf := func(offset int) int {
+		i := sort.Search(len(lines), func(i int) bool { return starts[i+1] > offset })
+		if i == len(lines) && i != 0 {
+			i--
+		}
+		return i
+	}
+
+	// chunk is a changed [from, to) lines range with its new lines.
+	type chunk struct {
+		from, to int
+		lines    []string
+	}
+	var chunks []*chunk
+	for i := 0; i < len(edits); {
+		c := &chunk{from: lineOf(edits[i].start)}
+		c.to = lineOf(intMax(edits[i].end-1, edits[i].start)) + 1
+		j := i + 1
+		for j < len(edits) && lineOf(edits[j].start) < c.to {
+			c.to = intMax(c.to, lineOf(intMax(edits[j].end-1, edits[j].start))+1)
+			j++
+		}
+		var text strings.Builder
+		offset := starts[c.from]
+		for _, e := range edits[i:j] {
+			text.Write(src[offset:e.start])
+			text.WriteString(e.replacement)
+			offset = e.end
+		}
+		text.Write(src[offset:starts[c.to]])
+		c.lines = splitLines([]byte(text.String()))
+		chunks = append(chunks, c)
+		i = j
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", filename, filename)
+	delta := 0
+	for i := 0; i < len(chunks); {
+		j := i + 1
+		for j < len(chunks) && chunks[j].from-chunks[j-1].to <= 2*diffContext {
+			j++
+		}
+		from := intMax(0, chunks[i].from-diffContext)
+		to := intMin(len(lines), chunks[j-1].to+diffContext)
+
+		var hunk strings.Builder
+		newLen := 0
+		prev := from
+		for _, c := range chunks[i:j] {
+			writeDiffLines(&hunk, ' ', lines[prev:c.from])
+			writeDiffLines(&hunk, '-', lines[c.from:c.to])
+			writeDiffLines(&hunk, '+', c.lines)
+			newLen += c.from - prev + len(c.lines)
+			prev = c.to
+		}
+		writeDiffLines(&hunk, ' ', lines[prev:to])
+		newLen += to - prev
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(from, to-from), hunkRange(from+delta, newLen))
+		out.WriteString(hunk.String())
+		for _, c := range chunks[i:j] {
+			delta += len(c.lines) - (c.to - c.from)
+		}
+		i = j
+	}
+	return out.String()
+}
+
+// splitLines splits data into lines, keeping the line endings.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) != 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines = append(lines, string(data[:i]))
+		data = data[i:]
+	}
+	return lines
+}
+
+func writeDiffLines(w *strings.Builder, prefix byte, lines []string) {
+	for _, l := range lines {
+		w.WriteByte(prefix)
+		w.WriteString(l)
+		if !strings.HasSuffix(l, "\n") {
+			w.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the 0-based lines range for the hunk header.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, n)
+	}
+}
+
+// runFixes analyzes the paths and applies the fixes of the reports.
+//
+// If write is set, the fixed files are rewritten in place.
+// If diff is set, the unified diffs of the fixes are printed to w.
+func runFixes(w io.Writer, paths []string, write, diff bool) error {
+	if linter.DefaultEncoding != "UTF-8" {
+		return fmt.Errorf("fixes are supported only for UTF-8 sources, got %s", linter.DefaultEncoding)
+	}
+
+	filter, err := newFixFilter()
+	if err != nil {
+		return err
+	}
+
+	go linter.MemoryLimiterThread()
+	linter.InitStubs()
+	linter.AnalysisFiles = paths
+	linter.ParseFilenames(linter.ReadFilenames(paths, nil))
+	meta.SetIndexingComplete(true)
+	filter.addReports(linter.ParseFilenames(linter.ReadFilenames(paths, filter.exclude)))
+
+	edits := filter.filter(fixes.take())
+	filenames := make([]string, 0, len(edits))
+	for filename := range edits {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		fixed, applied := applyEdits(src, edits[filename])
+		if skipped := len(edits[filename]) - len(applied); skipped != 0 {
+			log.Printf("%s: skipped %d overlapping fixes, run again to apply them", filename, skipped)
+		}
+		if len(applied) == 0 {
+			continue
+		}
+		if diff {
+			if _, err := io.WriteString(w, unifiedDiff(displayFilename(filename), src, applied)); err != nil {
+				return err
+			}
+		}
+		if write {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(filename, fixed, info.Mode()); err != nil {
+				return err
+			}
+			log.Printf("%s: applied %d fixes", filename, len(applied))
+		}
+	}
+	return nil
+}
+
+// displayFilename returns filename relative to the working directory if possible.
+func displayFilename(filename string) string {
+	wd, err := os.Getwd()
+	if err != nil || !isParentDir(wd, filename) {
+		return filename
+	}
+	rel, err := filepath.Rel(wd, filename)
+	if err != nil {
+		return filename
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/node/scalar"
+	"github.com/z7zmey/php-parser/node/stmt"
+)
+
+func TestApplyEdits(t *testing.T) {
+	tests := []struct {
+		src     string
+		edits   []*textEdit
+		want    string
+		applied int
+	}{
+		{`abc`, nil, `abc`, 0},
+		{`abc`, []*textEdit{{start: 1, end: 2, replacement: "xyz"}}, `axyzc`, 1},
+		{`abc`, []*textEdit{{start: 0, end: 0, replacement: "x"}}, `xabc`, 1},
+		{`abc`, []*textEdit{{start: 3, end: 3, replacement: "x"}}, `abcx`, 1},
+		{`abc`, []*textEdit{{start: 1, end: 3, replacement: ""}}, `a`, 1},
+
+		// Edits are applied in their start order.
+		{`abcd`, []*textEdit{
+			{start: 3, end: 4, replacement: "4"},
+			{start: 0, end: 1, replacement: "1"},
+		}, `1bc4`, 2},
+
+		// Adjacent edits don't overlap.
+		{`abcd`, []*textEdit{
+			{start: 0, end: 2, replacement: "12"},
+			{start: 2, end: 4, replacement: "34"},
+		}, `1234`, 2},
+
+		// Edit that overlaps an applied edit is skipped.
+		{`abcd`, []*textEdit{
+			{start: 1, end: 3, replacement: "23"},
+			{start: 0, end: 2, replacement: "12"},
+			{start: 2, end: 4, replacement: "34"},
+		}, `1234`, 2},
+		{`abcd`, []*textEdit{
+			{start: 0, end: 4, replacement: "1234"},
+			{start: 1, end: 2, replacement: "x"},
+		}, `1234`, 1},
+
+		// Invalid ranges are ignored.
+		{`abc`, []*textEdit{
+			{start: 2, end: 10, replacement: "x"},
+			{start: 2, end: 1, replacement: "x"},
+		}, `abc`, 0},
+	}
+
+	for _, test := range tests {
+		have, applied := applyEdits([]byte(test.src), test.edits)
+		if string(have) != test.want {
+			t.Errorf("%q: have %q, want %q", test.src, have, test.want)
+		}
+		if len(applied) != test.applied {
+			t.Errorf("%q: applied %d edits, want %d", test.src, len(applied), test.applied)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = string('a' + rune(i))
+	}
+	src := strings.Join(lines, "\n") + "\n"
+	offset := func(line int) int {
+		return strings.Index(src, lines[line-1]+"\n")
+	}
+
+	tests := []struct {
+		edits []*textEdit
+		want  string
+	}{
+		{
+			edits: []*textEdit{{start: offset(5), end: offset(5) + 1, replacement: "E"}},
+			want: `--- f.php
++++ f.php
+@@ -2,7 +2,7 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+`,
+		},
+
+		{
+			// Close edits are merged into one hunk,
+			// multiline replacements shift the new lines.
+			edits: []*textEdit{
+				{start: offset(1), end: offset(1) + 1, replacement: "A1\nA2"},
+				{start: offset(7), end: offset(8) + 1, replacement: "GH"},
+				{start: offset(19), end: offset(19) + 1, replacement: "S"},
+			},
+			want: `--- f.php
++++ f.php
+@@ -1,11 +1,11 @@
+-a
++A1
++A2
+ b
+ c
+ d
+ e
+ f
+-g
+-h
++GH
+ i
+ j
+ k
+@@ -16,5 +16,5 @@
+ p
+ q
+ r
+-s
++S
+ t
+`,
+		},
+
+		{
+			// Two edits on the last line, the second one replaces its newline.
+			edits: []*textEdit{
+				{start: offset(20), end: offset(20), replacement: "<"},
+				{start: offset(20) + 1, end: offset(20) + 2, replacement: ">"},
+			},
+			want: `--- f.php
++++ f.php
+@@ -17,4 +17,4 @@
+ q
+ r
+ s
+-t
++<t>
+\ No newline at end of file
+`,
+		},
+	}
+
+	for i, test := range tests {
+		have := unifiedDiff("f.php", []byte(src), test.edits)
+		if have != test.want {
+			t.Errorf("test %d:\nhave:\n%s\nwant:\n%s", i, have, test.want)
+		}
+	}
+}
+
+func TestFixFilter(t *testing.T) {
+	_, w := testParse(t, "disabled.php", `<?php
+	/** @linter disable */
+	function f($s1, $s2) { return strcmp($s1, $s2) === 0; }
+	`)
+
+	newFilter := func(exclude, allowDisable string, checks ...string) *fixFilter {
+		f := &fixFilter{excludeChecks: map[string]bool{}, disabledFiles: map[string]bool{}}
+		if exclude != "" {
+			f.exclude = regexp.MustCompile(exclude)
+		}
+		if allowDisable != "" {
+			f.allowDisable = regexp.MustCompile(allowDisable)
+		}
+		for _, name := range checks {
+			f.excludeChecks[name] = true
+		}
+		f.addReports(w.GetReports())
+		return f
+	}
+
+	tests := []struct {
+		filter *fixFilter
+		edit   *textEdit
+		want   bool
+	}{
+		{newFilter("", ""), &textEdit{filename: "a.php", checkName: "simplify"}, true},
+		{newFilter("", "", "badCall"), &textEdit{filename: "a.php", checkName: "simplify"}, true},
+		{newFilter("", "", "badCall", "simplify"), &textEdit{filename: "a.php", checkName: "simplify"}, false},
+		{newFilter(`^vendor/`, ""), &textEdit{filename: "vendor/a.php", checkName: "simplify"}, false},
+		{newFilter(`^vendor/`, ""), &textEdit{filename: "a.php", checkName: "simplify"}, true},
+		{newFilter("", ""), &textEdit{filename: "disabled.php", checkName: "simplify"}, false},
+		{newFilter("", `^a\.php$`), &textEdit{filename: "disabled.php", checkName: "simplify"}, false},
+		{newFilter("", `^disabled\.php$`), &textEdit{filename: "disabled.php", checkName: "simplify"}, true},
+	}
+
+	for i, test := range tests {
+		if have := test.filter.allowed(test.edit); have != test.want {
+			t.Errorf("test %d: %s edit of %s: have %v, want %v",
+				i, test.edit.checkName, test.edit.filename, have, test.want)
+		}
+	}
+}
+
+func TestPrintFix(t *testing.T) {
+	v := func(name string) node.Node {
+		return &expr.Variable{VarName: &node.Identifier{Value: name}}
+	}
+
+	tests := []struct {
+		n    node.Node
+		want string
+	}{
+		{&scalar.Lnumber{Value: "10"}, `10`},
+		{&binary.Identical{Left: v("a"), Right: v("b")}, `$a === $b`},
+		{&binary.Smaller{Left: &binary.Concat{Left: v("a"), Right: v("b")}, Right: v("c")}, `$a . $b < $c`},
+
+		// Parens are added where they're required.
+		{&binary.Mul{Left: &binary.Plus{Left: v("a"), Right: v("b")}, Right: v("c")}, `($a + $b) * $c`},
+		{&binary.Greater{Left: &expr.Ternary{Condition: v("a"), IfFalse: v("b")}, Right: v("c")}, `($a ?: $b) > $c`},
+
+		// Not an expression.
+		{&stmt.Nop{}, ``},
+	}
+
+	for _, test := range tests {
+		have, ok := printFix(test.n)
+		if ok != (test.want != "") || have != test.want {
+			t.Errorf("print %T: have %q (ok=%v), want %q", test.n, have, ok, test.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package astcmp implements structural comparison and hashing of
+// the php-parser AST nodes.
+//
+// Nodes are compared by their types, attributes and children.
+// Positions, comments and PHPDoc comments are ignored,
+// so the same code from different places compares equal.
+//
+// Every node is encoded into a canonical key in linear time.
+// Equal nodes have equal keys and hashes.
+package astcmp
+
+import (
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/walker"
+)
+
+// Config describes the node equivalence.
+//
+// The zero config is a strict structural equality.
+type Config struct {
+	// Commutative makes the operands order of the commutative
+	// operators insignificant, so "$a + $b" is equal to "$b + $a".
+	// "$a > $b" is treated as "$b < $a".
+	//
+	// Note that operands evaluation order is ignored in this mode,
+	// and "+" is considered to be commutative even though it's not
+	// for the arrays.
+	Commutative bool
+
+	// AbstractIdentifiers makes the identifier names insignificant
+	// as long as they're renamed consistently, so "$a + $a" is equal
+	// to "$b + $b", but not to "$a + $b".
+	// Identifiers include variable, property and method names.
+	AbstractIdentifiers bool
+}
+
+// Equal reports whether x and y are structurally equal.
+func Equal(x, y node.Node) bool { return Config{}.Equal(x, y) }
+
+// Hash returns a structural hash of n.
+func Hash(n node.Node) uint64 { return Config{}.Hash(n) }
+
+// Equal reports whether x and y are equal according to the config.
+func (cfg Config) Equal(x, y node.Node) bool {
+	return cfg.Key(x) == cfg.Key(y)
+}
+
+// Hash returns a hash of n that is consistent with the config equality.
+func (cfg Config) Hash(n node.Node) uint64 {
+	return KeyHash(cfg.Key(n))
+}
+
+// KeyHash returns a hash of the node key, which is equal to the node hash.
+// It's useful when both the key and the hash are needed.
+func KeyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Key returns a canonical encoding of n.
+// Nodes are equal according to the config iff their keys are equal.
+func (cfg Config) Key(n node.Node) string {
+	e := encoder{cfg: cfg, idents: map[string]int{}}
+	e.encode(n)
+	return e.buf.String()
+}
+
+type encoder struct {
+	cfg Config
+	buf strings.Builder
+
+	// idents maps identifier names to their order of appearance.
+	// Used only when identifiers are abstracted.
+	idents map[string]int
+}
+
+// subKey returns a key of n that shares identifiers numbering with e.
+func (e *encoder) subKey(n node.Node) string {
+	sub := encoder{cfg: e.cfg, idents: e.idents}
+	sub.encode(n)
+	return sub.buf.String()
+}
+
+func (e *encoder) encode(n node.Node) {
+	if n == nil {
+		e.buf.WriteString("nil;")
+		return
+	}
+
+	if e.cfg.Commutative {
+		if x, y, op, ok := flipComparison(n); ok {
+			e.encodeOperands(op, []node.Node{x, y})
+			return
+		}
+		if isCommutative(n) {
+			operands := flattenChain(n, nil)
+			keys := make([]string, len(operands))
+			for i, operand := range operands {
+				keys[i] = e.subKey(operand)
+			}
+			sort.Strings(keys)
+			e.writeType(n)
+			e.buf.WriteString(strconv.Itoa(len(keys)))
+			e.buf.WriteByte('{')
+			for _, k := range keys {
+				e.buf.WriteString(k)
+			}
+			e.buf.WriteByte('}')
+			return
+		}
+	}
+
+	e.writeType(n)
+	e.writeAttributes(n)
+	for _, group := range children(n) {
+		e.buf.WriteString(group.key)
+		e.buf.WriteByte('[')
+		for _, child := range group.nodes {
+			e.encode(child)
+		}
+		e.buf.WriteByte(']')
+	}
+	e.buf.WriteByte(';')
+}
+
+// encodeOperands encodes a binary op node with given operands.
+func (e *encoder) encodeOperands(op node.Node, operands []node.Node) {
+	e.writeType(op)
+	e.buf.WriteByte('{')
+	for _, x := range operands {
+		e.encode(x)
+	}
+	e.buf.WriteByte('}')
+}
+
+func (e *encoder) writeType(n node.Node) {
+	e.buf.WriteString(reflect.TypeOf(n).String())
+}
+
+func (e *encoder) writeAttributes(n node.Node) {
+	if id, ok := n.(*node.Identifier); ok && e.cfg.AbstractIdentifiers {
+		index, ok := e.idents[id.Value]
+		if !ok {
+			index = len(e.idents)
+			e.idents[id.Value] = index
+		}
+		e.buf.WriteString("(#" + strconv.Itoa(index) + ")")
+		return
+	}
+
+	attrs := n.Attributes()
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		if k == "PhpDocComment" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.buf.WriteByte('(')
+	for _, k := range keys {
+		e.buf.WriteString(k)
+		e.buf.WriteByte('=')
+		switch v := attrs[k].(type) {
+		case string:
+			e.buf.WriteString(strconv.Quote(v))
+		case bool:
+			e.buf.WriteString(strconv.FormatBool(v))
+		default:
+			e.buf.WriteString(strconv.Quote(reflect.ValueOf(v).String()))
+		}
+		e.buf.WriteByte(',')
+	}
+	e.buf.WriteByte(')')
+}
+
+// isCommutative reports whether n is a commutative and associative operator.
+func isCommutative(n node.Node) bool {
+	switch n.(type) {
+	case *binary.Plus, *binary.Mul,
+		*binary.BitwiseAnd, *binary.BitwiseOr, *binary.BitwiseXor,
+		*binary.BooleanAnd, *binary.BooleanOr,
+		*binary.LogicalAnd, *binary.LogicalOr, *binary.LogicalXor,
+		*binary.Equal, *binary.NotEqual, *binary.Identical, *binary.NotIdentical:
+		return true
+	}
+	return false
+}
+
+// flattenChain appends the operands of the same operators chain to dst,
+// so "$a + $b + $c" gives [$a, $b, $c].
+// Comparison operators are not associative, so they're never flattened.
+func flattenChain(n node.Node, dst []node.Node) []node.Node {
+	x, y := binaryOperands(n)
+	for _, operand := range []node.Node{x, y} {
+		switch operand.(type) {
+		case *binary.Equal, *binary.NotEqual, *binary.Identical, *binary.NotIdentical:
+			dst = append(dst, operand)
+			continue
+		}
+		if reflect.TypeOf(operand) == reflect.TypeOf(n) {
+			dst = flattenChain(operand, dst)
+		} else {
+			dst = append(dst, operand)
+		}
+	}
+	return dst
+}
+
+// flipComparison converts "x > y" to "y < x" and "x >= y" to "y <= x".
+func flipComparison(n node.Node) (x, y, op node.Node, ok bool) {
+	switch n := n.(type) {
+	case *binary.Greater:
+		return n.Right, n.Left, &binary.Smaller{}, true
+	case *binary.GreaterOrEqual:
+		return n.Right, n.Left, &binary.SmallerOrEqual{}, true
+	case *binary.Smaller:
+		return n.Left, n.Right, n, true
+	case *binary.SmallerOrEqual:
+		return n.Left, n.Right, n, true
+	}
+	return nil, nil, nil, false
+}
+
+func binaryOperands(n node.Node) (x, y node.Node) {
+	for _, group := range children(n) {
+		switch group.key {
+		case "Left":
+			x = group.nodes[0]
+		case "Right":
+			y = group.nodes[0]
+		}
+	}
+	return x, y
+}
+
+// childGroup is a named group of the node children, like "Stmts".
+type childGroup struct {
+	key   string
+	nodes []node.Node
+}
+
+func (g *childGroup) EnterNode(w walker.Walkable) bool {
+	g.nodes = append(g.nodes, w.(node.Node))
+	return false
+}
+
+func (g *childGroup) GetChildrenVisitor(key string) walker.Visitor { return nil }
+func (g *childGroup) LeaveNode(w walker.Walkable)                  {}
+
+// childrenCollector collects the direct children of the walked node.
+type childrenCollector struct {
+	groups []*childGroup
+}
+
+func (c *childrenCollector) EnterNode(w walker.Walkable) bool { return true }
+func (c *childrenCollector) LeaveNode(w walker.Walkable)      {}
+
+func (c *childrenCollector) GetChildrenVisitor(key string) walker.Visitor {
+	g := &childGroup{key: key}
+	c.groups = append(c.groups, g)
+	return g
+}
+
+// children returns the n children grouped by the node fields.
+// Groups are ordered in the same way they're walked.
+func children(n node.Node) []*childGroup {
+	var c childrenCollector
+	n.Walk(&c)
+	return c.groups
+}
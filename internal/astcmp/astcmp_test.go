@@ -0,0 +1,122 @@
+package astcmp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/php7"
+)
+
+func parseExpr(t *testing.T, code string) node.Node {
+	return parseStmt(t, code+";").(*stmt.Expression).Expr
+}
+
+func parseStmt(t *testing.T, code string) node.Node {
+	p := php7.NewParser(strings.NewReader("<?php "+code), "test.php")
+	p.Parse()
+	if errs := p.GetErrors(); len(errs) != 0 {
+		t.Fatalf("parse %q: %v", code, errs[0])
+	}
+	return p.GetRootNode().(*stmt.StmtList).Stmts[0]
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		x, y        string
+		strict      bool
+		commutative bool
+	}{
+		{`$a`, `$a`, true, true},
+		{`$a`, `$b`, false, false},
+		{`$a[0]->b`, `$a[0]  ->  b`, true, true},
+		{`f(1, 2)`, `f(1, 2)`, true, true},
+		{`f(1, 2)`, `f(2, 1)`, false, false},
+		{`f(1)`, `g(1)`, false, false},
+		{`'a'`, `"a"`, false, false},
+		{`1`, `1.0`, false, false},
+		{`$x ? 1 : 2`, `$x ? 1 : 2`, true, true},
+		{`$x ?: 2`, `$x ? $x : 2`, false, false},
+		{`[1, 2]`, `[1, 2]`, true, true},
+		{`array(1, 2)`, `[1, 2]`, false, false},
+		{`function() { return 1; }`, `function() { return 1; }`, true, true},
+		{`/** doc */ function() {}`, `function() {}`, true, true},
+		{`function(&$x) {}`, `function($x) {}`, false, false},
+
+		{`$a + $b`, `$b + $a`, false, true},
+		{`$a + $b + $c`, `$c + ($b + $a)`, false, true},
+		{`$a - $b`, `$b - $a`, false, false},
+		{`$a . $b`, `$b . $a`, false, false},
+		{`$a == $b`, `$b == $a`, false, true},
+		{`($a == $b) == $c`, `$a == ($b == $c)`, false, false},
+		{`$a && $b || $c`, `$c || $b && $a`, false, true},
+		{`$a && $b || $c`, `$a && ($b || $c)`, false, false},
+		{`$a > $b`, `$b < $a`, false, true},
+		{`$a >= $b`, `$b <= $a`, false, true},
+		{`$a > $b`, `$a < $b`, false, false},
+		{`f($a * 2)`, `f(2 * $a)`, false, true},
+	}
+
+	commutative := Config{Commutative: true}
+	for _, test := range tests {
+		x := parseExpr(t, test.x)
+		y := parseExpr(t, test.y)
+		if have := Equal(x, y); have != test.strict {
+			t.Errorf("Equal(%s, %s): have %v, want %v", test.x, test.y, have, test.strict)
+		}
+		if have := commutative.Equal(x, y); have != test.commutative {
+			t.Errorf("commutative Equal(%s, %s): have %v, want %v", test.x, test.y, have, test.commutative)
+		}
+		if test.strict && Hash(x) != Hash(y) {
+			t.Errorf("Hash(%s) != Hash(%s)", test.x, test.y)
+		}
+		if test.commutative && commutative.Hash(x) != commutative.Hash(y) {
+			t.Errorf("commutative Hash(%s) != Hash(%s)", test.x, test.y)
+		}
+	}
+}
+
+func TestEqualStmt(t *testing.T) {
+	x := parseStmt(t, `if ($x) { echo 1; /* comment */ } else { echo 2; }`)
+	y := parseStmt(t, `if ($x) {
+		// A comment.
+		echo 1;
+	} else {
+		echo 2;
+	}`)
+	z := parseStmt(t, `if ($x) { echo 2; } else { echo 1; }`)
+	if !Equal(x, y) {
+		t.Errorf("statements that differ in formatting are expected to be equal")
+	}
+	if Equal(x, z) {
+		t.Errorf("statements with swapped branches are not expected to be equal")
+	}
+}
+
+func TestAbstractIdentifiers(t *testing.T) {
+	tests := []struct {
+		x, y  string
+		equal bool
+	}{
+		{`$a + $a`, `$b + $b`, true},
+		{`$a + $b`, `$b + $a`, true},
+		{`$a + $a`, `$a + $b`, false},
+		{`$a->x($a)`, `$b->y($b)`, true},
+		{`$a->a($a)`, `$b->y($b)`, false},
+		{`f($a)`, `g($a)`, false},
+		{`$a + 1`, `$a + 2`, false},
+	}
+
+	cfg := Config{AbstractIdentifiers: true}
+	for _, test := range tests {
+		x := parseExpr(t, test.x)
+		y := parseExpr(t, test.y)
+		if have := cfg.Equal(x, y); have != test.equal {
+			t.Errorf("Equal(%s, %s): have %v, want %v", test.x, test.y, have, test.equal)
+		}
+		if test.equal && cfg.Hash(x) != cfg.Hash(y) {
+			t.Errorf("Hash(%s) != Hash(%s)", test.x, test.y)
+		}
+	}
+}
@@ -0,0 +1,1159 @@
+// Package astprint renders php-parser AST nodes as canonical PHP source.
+//
+// The parser doesn't preserve parens, so they're inserted
+// according to the operators precedence and associativity,
+// only where they're required. Printed code parses back
+// to the same AST, but the original formatting is lost:
+// operators are surrounded by single spaces, statements are
+// indented and blocks are always braced.
+package astprint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/assign"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/node/expr/cast"
+	"github.com/z7zmey/php-parser/node/name"
+	"github.com/z7zmey/php-parser/node/scalar"
+	"github.com/z7zmey/php-parser/node/stmt"
+)
+
+// Config describes the printing options.
+//
+// The zero config prints the whole node with 4 spaces indentation.
+type Config struct {
+	// MaxLen is a maximum length of the printed code in bytes.
+	// Longer code is truncated and "..." is appended to it.
+	// Zero means no limit.
+	MaxLen int
+
+	// Indent is a string that is used for every indentation level.
+	// Empty indent means 4 spaces.
+	Indent string
+}
+
+// Print returns the source code of n.
+func Print(n node.Node) string { return Config{}.Print(n) }
+
+// Print returns the source code of n according to the config.
+func (cfg Config) Print(n node.Node) string {
+	p := &printer{cfg: cfg}
+	if p.cfg.Indent == "" {
+		p.cfg.Indent = "    "
+	}
+	p.print(n)
+	if p.truncated {
+		return p.buf.String() + "..."
+	}
+	return p.buf.String()
+}
+
+// Operators precedence, from the lowest to the highest.
+// Atoms are the variables, literals, calls and other
+// expressions that never need to be parenthesized.
+const (
+	precLogicalOr = iota
+	precLogicalXor
+	precLogicalAnd
+	precPrint // print, yield, include and require
+	precAssign
+	precTernary
+	precCoalesce
+	precBooleanOr
+	precBooleanAnd
+	precBitwiseOr
+	precBitwiseXor
+	precBitwiseAnd
+	precEquality
+	precComparison
+	precShift
+	precAdditive
+	precMultiplicative
+	precBooleanNot
+	precInstanceOf
+	precUnary // casts, "@", "~", unary "+" and "-", "++" and "--"
+	precPow
+	precNew // new and clone
+	precAtom
+)
+
+// assoc is an operator associativity.
+type assoc int
+
+const (
+	assocLeft assoc = iota
+	assocRight
+	assocNone
+)
+
+// binaryOp describes a binary operator node.
+type binaryOp struct {
+	token string
+	prec  int
+	assoc assoc
+}
+
+// binaryOperands returns the binary operator node operands along with
+// the operator description. ok is false if n is not a binary operator.
+func binaryOperands(n node.Node) (x, y node.Node, op binaryOp, ok bool) {
+	switch n := n.(type) {
+	case *binary.LogicalOr:
+		return n.Left, n.Right, binaryOp{"or", precLogicalOr, assocLeft}, true
+	case *binary.LogicalXor:
+		return n.Left, n.Right, binaryOp{"xor", precLogicalXor, assocLeft}, true
+	case *binary.LogicalAnd:
+		return n.Left, n.Right, binaryOp{"and", precLogicalAnd, assocLeft}, true
+	case *binary.Coalesce:
+		return n.Left, n.Right, binaryOp{"??", precCoalesce, assocRight}, true
+	case *binary.BooleanOr:
+		return n.Left, n.Right, binaryOp{"||", precBooleanOr, assocLeft}, true
+	case *binary.BooleanAnd:
+		return n.Left, n.Right, binaryOp{"&&", precBooleanAnd, assocLeft}, true
+	case *binary.BitwiseOr:
+		return n.Left, n.Right, binaryOp{"|", precBitwiseOr, assocLeft}, true
+	case *binary.BitwiseXor:
+		return n.Left, n.Right, binaryOp{"^", precBitwiseXor, assocLeft}, true
+	case *binary.BitwiseAnd:
+		return n.Left, n.Right, binaryOp{"&", precBitwiseAnd, assocLeft}, true
+	case *binary.Equal:
+		return n.Left, n.Right, binaryOp{"==", precEquality, assocNone}, true
+	case *binary.NotEqual:
+		return n.Left, n.Right, binaryOp{"!=", precEquality, assocNone}, true
+	case *binary.Identical:
+		return n.Left, n.Right, binaryOp{"===", precEquality, assocNone}, true
+	case *binary.NotIdentical:
+		return n.Left, n.Right, binaryOp{"!==", precEquality, assocNone}, true
+	case *binary.Spaceship:
+		return n.Left, n.Right, binaryOp{"<=>", precEquality, assocNone}, true
+	case *binary.Smaller:
+		return n.Left, n.Right, binaryOp{"<", precComparison, assocNone}, true
+	case *binary.SmallerOrEqual:
+		return n.Left, n.Right, binaryOp{"<=", precComparison, assocNone}, true
+	case *binary.Greater:
+		return n.Left, n.Right, binaryOp{">", precComparison, assocNone}, true
+	case *binary.GreaterOrEqual:
+		return n.Left, n.Right, binaryOp{">=", precComparison, assocNone}, true
+	case *binary.ShiftLeft:
+		return n.Left, n.Right, binaryOp{"<<", precShift, assocLeft}, true
+	case *binary.ShiftRight:
+		return n.Left, n.Right, binaryOp{">>", precShift, assocLeft}, true
+	case *binary.Plus:
+		return n.Left, n.Right, binaryOp{"+", precAdditive, assocLeft}, true
+	case *binary.Minus:
+		return n.Left, n.Right, binaryOp{"-", precAdditive, assocLeft}, true
+	case *binary.Concat:
+		return n.Left, n.Right, binaryOp{".", precAdditive, assocLeft}, true
+	case *binary.Mul:
+		return n.Left, n.Right, binaryOp{"*", precMultiplicative, assocLeft}, true
+	case *binary.Div:
+		return n.Left, n.Right, binaryOp{"/", precMultiplicative, assocLeft}, true
+	case *binary.Mod:
+		return n.Left, n.Right, binaryOp{"%", precMultiplicative, assocLeft}, true
+	case *binary.Pow:
+		return n.Left, n.Right, binaryOp{"**", precPow, assocRight}, true
+	case *expr.InstanceOf:
+		return n.Expr, n.Class, binaryOp{"instanceof", precInstanceOf, assocNone}, true
+
+	case *assign.Assign:
+		return n.Variable, n.Expression, binaryOp{"=", precAssign, assocRight}, true
+	case *assign.Reference:
+		return n.Variable, n.Expression, binaryOp{"=&", precAssign, assocRight}, true
+	case *assign.BitwiseAnd:
+		return n.Variable, n.Expression, binaryOp{"&=", precAssign, assocRight}, true
+	case *assign.BitwiseOr:
+		return n.Variable, n.Expression, binaryOp{"|=", precAssign, assocRight}, true
+	case *assign.BitwiseXor:
+		return n.Variable, n.Expression, binaryOp{"^=", precAssign, assocRight}, true
+	case *assign.Concat:
+		return n.Variable, n.Expression, binaryOp{".=", precAssign, assocRight}, true
+	case *assign.Div:
+		return n.Variable, n.Expression, binaryOp{"/=", precAssign, assocRight}, true
+	case *assign.Minus:
+		return n.Variable, n.Expression, binaryOp{"-=", precAssign, assocRight}, true
+	case *assign.Mod:
+		return n.Variable, n.Expression, binaryOp{"%=", precAssign, assocRight}, true
+	case *assign.Mul:
+		return n.Variable, n.Expression, binaryOp{"*=", precAssign, assocRight}, true
+	case *assign.Plus:
+		return n.Variable, n.Expression, binaryOp{"+=", precAssign, assocRight}, true
+	case *assign.Pow:
+		return n.Variable, n.Expression, binaryOp{"**=", precAssign, assocRight}, true
+	case *assign.ShiftLeft:
+		return n.Variable, n.Expression, binaryOp{"<<=", precAssign, assocRight}, true
+	case *assign.ShiftRight:
+		return n.Variable, n.Expression, binaryOp{">>=", precAssign, assocRight}, true
+	}
+	return nil, nil, binaryOp{}, false
+}
+
+// prefixOperand returns the prefix operator node operand along with
+// the operator token and precedence. ok is false if n is not a prefix operator.
+func prefixOperand(n node.Node) (x node.Node, token string, prec int, ok bool) {
+	switch n := n.(type) {
+	case *expr.BooleanNot:
+		return n.Expr, "!", precBooleanNot, true
+	case *expr.BitwiseNot:
+		return n.Expr, "~", precUnary, true
+	case *expr.UnaryMinus:
+		return n.Expr, "-", precUnary, true
+	case *expr.UnaryPlus:
+		return n.Expr, "+", precUnary, true
+	case *expr.ErrorSuppress:
+		return n.Expr, "@", precUnary, true
+	case *expr.PreInc:
+		return n.Variable, "++", precUnary, true
+	case *expr.PreDec:
+		return n.Variable, "--", precUnary, true
+	case *cast.Array:
+		return n.Expr, "(array)", precUnary, true
+	case *cast.Bool:
+		return n.Expr, "(bool)", precUnary, true
+	case *cast.Double:
+		return n.Expr, "(float)", precUnary, true
+	case *cast.Int:
+		return n.Expr, "(int)", precUnary, true
+	case *cast.Object:
+		return n.Expr, "(object)", precUnary, true
+	case *cast.String:
+		return n.Expr, "(string)", precUnary, true
+	case *cast.Unset:
+		return n.Expr, "(unset)", precUnary, true
+	case *expr.Clone:
+		return n.Expr, "clone ", precNew, true
+	case *expr.Print:
+		return n.Expr, "print ", precPrint, true
+	case *expr.YieldFrom:
+		return n.Expr, "yield from ", precPrint, true
+	case *expr.Include:
+		return n.Expr, "include ", precPrint, true
+	case *expr.IncludeOnce:
+		return n.Expr, "include_once ", precPrint, true
+	case *expr.Require:
+		return n.Expr, "require ", precPrint, true
+	case *expr.RequireOnce:
+		return n.Expr, "require_once ", precPrint, true
+	}
+	return nil, "", 0, false
+}
+
+// precedence returns the n expression precedence.
+func precedence(n node.Node) int {
+	if _, _, op, ok := binaryOperands(n); ok {
+		return op.prec
+	}
+	if _, _, prec, ok := prefixOperand(n); ok {
+		return prec
+	}
+	switch n.(type) {
+	case *expr.Ternary:
+		return precTernary
+	case *expr.Yield:
+		return precPrint
+	case *expr.PostInc, *expr.PostDec:
+		return precUnary
+	case *expr.New:
+		return precNew
+	}
+	return precAtom
+}
+
+// isDereferencable reports whether n can be used without parens
+// as a base of the property fetch, method call or function call.
+func isDereferencable(n node.Node) bool {
+	switch n.(type) {
+	case *expr.Variable, *expr.ArrayDimFetch, *expr.PropertyFetch,
+		*expr.StaticPropertyFetch, *expr.FunctionCall, *expr.MethodCall,
+		*expr.StaticCall, *name.Name, *name.FullyQualified, *name.Relative:
+		return true
+	}
+	return false
+}
+
+// isIndexable reports whether n can be used without parens
+// as a base of the array element fetch.
+func isIndexable(n node.Node) bool {
+	switch n.(type) {
+	case *expr.ConstFetch, *expr.ClassConstFetch, *expr.ShortArray,
+		*expr.Array, *scalar.String, *scalar.Encapsed:
+		return true
+	}
+	return isDereferencable(n)
+}
+
+type printer struct {
+	cfg Config
+
+	buf       strings.Builder
+	depth     int
+	truncated bool
+}
+
+func (p *printer) write(s string) {
+	if p.truncated {
+		return
+	}
+	if p.cfg.MaxLen > 0 && p.buf.Len()+len(s) > p.cfg.MaxLen {
+		s = s[:p.cfg.MaxLen-p.buf.Len()]
+		// Don't cut the multibyte characters.
+		for len(s) != 0 && !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		p.buf.WriteString(s)
+		p.truncated = true
+		return
+	}
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.write("\n")
+	p.write(strings.Repeat(p.cfg.Indent, p.depth))
+}
+
+// printList prints the nodes separated by the sep.
+func (p *printer) printList(sep string, list []node.Node) {
+	for i, n := range list {
+		if i != 0 {
+			p.write(sep)
+		}
+		p.print(n)
+	}
+}
+
+// mixesConcat reports whether one of x and y is "." and another is "+" or "-".
+//
+// PHP 8 gives "+" and "-" a higher precedence than ".", so such
+// operands are always parenthesized to keep the code unambiguous.
+func mixesConcat(x, y node.Node) bool {
+	isAdditive := func(n node.Node) bool {
+		switch n.(type) {
+		case *binary.Plus, *binary.Minus:
+			return true
+		}
+		return false
+	}
+	_, xConcat := x.(*binary.Concat)
+	_, yConcat := y.(*binary.Concat)
+	return (xConcat && isAdditive(y)) || (yConcat && isAdditive(x))
+}
+
+// printOperand prints n parenthesized if its precedence is lower than prec.
+func (p *printer) printOperand(n node.Node, prec int) {
+	if precedence(n) < prec {
+		p.write("(")
+		p.print(n)
+		p.write(")")
+		return
+	}
+	p.print(n)
+}
+
+// printBase prints the dereferenced expression, parenthesized if required.
+func (p *printer) printBase(n node.Node, ok func(node.Node) bool) {
+	if ok(n) {
+		p.print(n)
+		return
+	}
+	p.write("(")
+	p.print(n)
+	p.write(")")
+}
+
+// printMember prints the property or method name.
+// Dynamic names are printed inside the braces.
+func (p *printer) printMember(n node.Node) {
+	switch n.(type) {
+	case *node.Identifier, *expr.Variable:
+		p.print(n)
+	default:
+		p.write("{")
+		p.print(n)
+		p.write("}")
+	}
+}
+
+// printEncapsed prints the interpolated string parts.
+// All expressions are printed inside the braces.
+func (p *printer) printEncapsed(parts []node.Node) {
+	for _, part := range parts {
+		if s, ok := part.(*scalar.EncapsedStringPart); ok {
+			p.write(s.Value)
+			continue
+		}
+		p.write("{")
+		p.print(part)
+		p.write("}")
+	}
+}
+
+func (p *printer) printCall(fn node.Node, args []node.Node) {
+	p.print(fn)
+	p.write("(")
+	p.printList(", ", args)
+	p.write(")")
+}
+
+func (p *printer) print(n node.Node) {
+	if p.truncated || n == nil {
+		return
+	}
+
+	if x, y, op, ok := binaryOperands(n); ok {
+		xprec, yprec := op.prec, op.prec
+		switch op.assoc {
+		case assocLeft:
+			yprec++
+		case assocRight:
+			xprec++
+		case assocNone:
+			xprec++
+			yprec++
+		}
+		if mixesConcat(n, x) {
+			xprec = precAtom
+		}
+		if mixesConcat(n, y) {
+			yprec = precAtom
+		}
+		p.printOperand(x, xprec)
+		p.write(" " + op.token + " ")
+		p.printOperand(y, yprec)
+		return
+	}
+	if x, token, prec, ok := prefixOperand(n); ok {
+		p.write(token)
+		// Avoid "--" and "++" tokens for the nested signs.
+		switch x.(type) {
+		case *expr.UnaryMinus, *expr.PreDec:
+			if token == "-" {
+				prec = precAtom
+			}
+		case *expr.UnaryPlus, *expr.PreInc:
+			if token == "+" {
+				prec = precAtom
+			}
+		}
+		p.printOperand(x, prec)
+		return
+	}
+
+	switch n := n.(type) {
+	case *node.Identifier:
+		p.write(n.Value)
+	case *node.Argument:
+		if n.IsReference {
+			p.write("&")
+		}
+		if n.Variadic {
+			p.write("...")
+		}
+		p.print(n.Expr)
+	case *node.Parameter:
+		if n.VariableType != nil {
+			p.print(n.VariableType)
+			p.write(" ")
+		}
+		if n.ByRef {
+			p.write("&")
+		}
+		if n.Variadic {
+			p.write("...")
+		}
+		p.print(n.Variable)
+		if n.DefaultValue != nil {
+			p.write(" = ")
+			p.print(n.DefaultValue)
+		}
+	case *node.Nullable:
+		p.write("?")
+		p.print(n.Expr)
+
+	case *name.NamePart:
+		p.write(n.Value)
+	case *name.Name:
+		p.printList(`\`, n.Parts)
+	case *name.FullyQualified:
+		p.write(`\`)
+		p.printList(`\`, n.Parts)
+	case *name.Relative:
+		p.write(`namespace\`)
+		p.printList(`\`, n.Parts)
+
+	case *scalar.Lnumber:
+		p.write(n.Value)
+	case *scalar.Dnumber:
+		p.write(n.Value)
+	case *scalar.String:
+		p.write(n.Value)
+	case *scalar.MagicConstant:
+		p.write(n.Value)
+	case *scalar.EncapsedStringPart:
+		p.write(n.Value)
+	case *scalar.Encapsed:
+		p.write(`"`)
+		p.printEncapsed(n.Parts)
+		p.write(`"`)
+	case *scalar.Heredoc:
+		p.write("<<<" + n.Label + "\n")
+		p.printEncapsed(n.Parts)
+		// The closing identifier must be on its own line.
+		p.write(strings.Trim(n.Label, `"'`) + "\n")
+
+	case *expr.Variable:
+		p.write("$")
+		switch name := n.VarName.(type) {
+		case *node.Identifier, *expr.Variable:
+			p.print(name)
+		default:
+			p.write("{")
+			p.print(name)
+			p.write("}")
+		}
+	case *expr.ConstFetch:
+		p.print(n.Constant)
+	case *expr.ClassConstFetch:
+		p.printBase(n.Class, isDereferencable)
+		p.write("::")
+		p.print(n.ConstantName)
+	case *expr.ArrayDimFetch:
+		p.printBase(n.Variable, isIndexable)
+		p.write("[")
+		p.print(n.Dim)
+		p.write("]")
+	case *expr.PropertyFetch:
+		p.printBase(n.Variable, isDereferencable)
+		p.write("->")
+		p.printMember(n.Property)
+	case *expr.StaticPropertyFetch:
+		p.printBase(n.Class, isDereferencable)
+		p.write("::")
+		p.print(n.Property)
+	case *expr.FunctionCall:
+		p.printBase(n.Function, isDereferencable)
+		p.write("(")
+		p.printList(", ", n.Arguments)
+		p.write(")")
+	case *expr.MethodCall:
+		p.printBase(n.Variable, isDereferencable)
+		p.write("->")
+		p.printMember(n.Method)
+		p.write("(")
+		p.printList(", ", n.Arguments)
+		p.write(")")
+	case *expr.StaticCall:
+		p.printBase(n.Class, isDereferencable)
+		p.write("::")
+		p.printMember(n.Call)
+		p.write("(")
+		p.printList(", ", n.Arguments)
+		p.write(")")
+	case *expr.New:
+		p.write("new ")
+		if class, ok := n.Class.(*stmt.Class); ok {
+			p.printClass("class", class)
+			return
+		}
+		p.print(n.Class)
+		// The parser keeps nil arguments for "new A" without parens.
+		if n.Arguments != nil {
+			p.write("(")
+			p.printList(", ", n.Arguments)
+			p.write(")")
+		}
+
+	case *expr.PostInc:
+		p.printOperand(n.Variable, precAtom)
+		p.write("++")
+	case *expr.PostDec:
+		p.printOperand(n.Variable, precAtom)
+		p.write("--")
+	case *expr.Ternary:
+		// Nested ternary operators are always parenthesized.
+		p.printOperand(n.Condition, precTernary+1)
+		if n.IfTrue == nil {
+			p.write(" ?: ")
+		} else {
+			p.write(" ? ")
+			p.print(n.IfTrue)
+			p.write(" : ")
+		}
+		p.printOperand(n.IfFalse, precTernary+1)
+	case *expr.Yield:
+		p.write("yield")
+		if n.Key != nil {
+			p.write(" ")
+			p.printOperand(n.Key, precPrint+1)
+			p.write(" =>")
+		}
+		if n.Value != nil {
+			p.write(" ")
+			p.printOperand(n.Value, precPrint+1)
+		}
+
+	case *expr.Array:
+		p.write("array(")
+		p.printList(", ", n.Items)
+		p.write(")")
+	case *expr.ShortArray:
+		p.write("[")
+		p.printList(", ", n.Items)
+		p.write("]")
+	case *expr.List:
+		p.write("list(")
+		p.printList(", ", n.Items)
+		p.write(")")
+	case *expr.ShortList:
+		p.write("[")
+		p.printList(", ", n.Items)
+		p.write("]")
+	case *expr.ArrayItem:
+		if n.Key != nil {
+			p.print(n.Key)
+			p.write(" => ")
+		}
+		if n.ByRef {
+			p.write("&")
+		}
+		p.print(n.Val)
+
+	case *expr.Isset:
+		p.write("isset(")
+		p.printList(", ", n.Variables)
+		p.write(")")
+	case *expr.Empty:
+		p.write("empty(")
+		p.print(n.Expr)
+		p.write(")")
+	case *expr.Eval:
+		p.write("eval(")
+		p.print(n.Expr)
+		p.write(")")
+	case *expr.Exit:
+		p.write("exit")
+		if n.Expr != nil {
+			p.write("(")
+			p.print(n.Expr)
+			p.write(")")
+		}
+	case *expr.Die:
+		p.write("die")
+		if n.Expr != nil {
+			p.write("(")
+			p.print(n.Expr)
+			p.write(")")
+		}
+	case *expr.ShellExec:
+		p.write("`")
+		p.printEncapsed(n.Parts)
+		p.write("`")
+
+	case *expr.Closure:
+		if n.Static {
+			p.write("static ")
+		}
+		p.write("function ")
+		if n.ReturnsRef {
+			p.write("&")
+		}
+		p.write("(")
+		p.printList(", ", n.Params)
+		p.write(")")
+		if len(n.Uses) != 0 {
+			p.write(" use (")
+			p.printList(", ", n.Uses)
+			p.write(")")
+		}
+		p.printReturnType(n.ReturnType)
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *expr.ClosureUse:
+		if n.ByRef {
+			p.write("&")
+		}
+		p.print(n.Variable)
+
+	default:
+		p.printStmt(n)
+	}
+}
+
+func (p *printer) printReturnType(typ node.Node) {
+	if typ != nil {
+		p.write(": ")
+		p.print(typ)
+	}
+}
+
+// printBlock prints the braced statements list.
+func (p *printer) printBlock(stmts []node.Node) {
+	p.write("{")
+	if len(stmts) != 0 {
+		p.depth++
+		for _, s := range stmts {
+			p.newline()
+			p.print(s)
+		}
+		p.depth--
+		p.newline()
+	}
+	p.write("}")
+}
+
+// printBody prints the control statement body.
+// It returns whether the body is a braced block.
+func (p *printer) printBody(body node.Node) bool {
+	switch body := body.(type) {
+	case *stmt.StmtList:
+		p.write(" ")
+		p.printBlock(body.Stmts)
+		return true
+	case *stmt.Nop:
+		p.write(";")
+	default:
+		p.depth++
+		p.newline()
+		p.print(body)
+		p.depth--
+	}
+	return false
+}
+
+// printAltBody prints the alternative syntax statement body
+// that is followed by the closing keyword.
+func (p *printer) printAltBody(body node.Node, end string) {
+	p.write(":")
+	p.printAltStmts(body)
+	if end != "" {
+		p.newline()
+		p.write(end)
+	}
+}
+
+func (p *printer) printAltStmts(body node.Node) {
+	list, ok := body.(*stmt.StmtList)
+	if !ok {
+		list = &stmt.StmtList{Stmts: []node.Node{body}}
+	}
+	p.depth++
+	for _, s := range list.Stmts {
+		p.newline()
+		p.print(s)
+	}
+	p.depth--
+}
+
+// printClass prints the class declaration or the anonymous class
+// that starts with the given keyword.
+func (p *printer) printClass(keyword string, n *stmt.Class) {
+	if len(n.Modifiers) != 0 {
+		p.printList(" ", n.Modifiers)
+		p.write(" ")
+	}
+	p.write(keyword)
+	if n.ClassName != nil {
+		p.write(" ")
+		p.print(n.ClassName)
+	}
+	if n.Args != nil {
+		p.write("(")
+		p.printList(", ", n.Args)
+		p.write(")")
+	}
+	if n.Extends != nil {
+		p.write(" extends ")
+		p.print(n.Extends)
+	}
+	if len(n.Implements) != 0 {
+		p.write(" implements ")
+		p.printList(", ", n.Implements)
+	}
+	p.write(" ")
+	p.printBlock(n.Stmts)
+}
+
+func (p *printer) printStmt(n node.Node) {
+	switch n := n.(type) {
+	case *stmt.StmtList:
+		p.printBlock(n.Stmts)
+	case *stmt.Nop:
+		p.write(";")
+	case *stmt.Expression:
+		p.print(n.Expr)
+		p.write(";")
+	case *stmt.Echo:
+		p.write("echo ")
+		p.printList(", ", n.Exprs)
+		p.write(";")
+	case *stmt.Return:
+		p.printKeywordStmt("return", n.Expr)
+	case *stmt.Break:
+		p.printKeywordStmt("break", n.Expr)
+	case *stmt.Continue:
+		p.printKeywordStmt("continue", n.Expr)
+	case *stmt.Throw:
+		p.printKeywordStmt("throw", n.Expr)
+	case *stmt.Goto:
+		p.printKeywordStmt("goto", n.Label)
+	case *stmt.Label:
+		p.print(n.LabelName)
+		p.write(":")
+	case *stmt.Global:
+		p.write("global ")
+		p.printList(", ", n.Vars)
+		p.write(";")
+	case *stmt.Static:
+		p.write("static ")
+		p.printList(", ", n.Vars)
+		p.write(";")
+	case *stmt.StaticVar:
+		p.print(n.Variable)
+		if n.Expr != nil {
+			p.write(" = ")
+			p.print(n.Expr)
+		}
+	case *stmt.Unset:
+		p.write("unset(")
+		p.printList(", ", n.Vars)
+		p.write(");")
+	case *stmt.InlineHtml:
+		p.write("?>" + n.Value + "<?php")
+	case *stmt.HaltCompiler:
+		p.write("__halt_compiler();")
+
+	case *stmt.If:
+		p.write("if (")
+		p.print(n.Cond)
+		p.write(")")
+		braced := p.printBody(n.Stmt)
+		for _, elseif := range n.ElseIf {
+			p.printElse(braced)
+			braced = p.printElseIf(elseif)
+		}
+		if n.Else != nil {
+			p.printElse(braced)
+			p.print(n.Else)
+		}
+	case *stmt.ElseIf:
+		p.printElseIf(n)
+	case *stmt.Else:
+		p.write("else")
+		if _, ok := n.Stmt.(*stmt.If); ok {
+			p.write(" ")
+			p.print(n.Stmt)
+			return
+		}
+		p.printBody(n.Stmt)
+	case *stmt.AltIf:
+		p.write("if (")
+		p.print(n.Cond)
+		p.write(")")
+		p.printAltBody(n.Stmt, "")
+		for _, elseif := range n.ElseIf {
+			p.newline()
+			p.print(elseif)
+		}
+		if n.Else != nil {
+			p.newline()
+			p.print(n.Else)
+		}
+		p.newline()
+		p.write("endif;")
+	case *stmt.AltElseIf:
+		p.write("elseif (")
+		p.print(n.Cond)
+		p.write(")")
+		p.printAltBody(n.Stmt, "")
+	case *stmt.AltElse:
+		p.write("else")
+		p.printAltBody(n.Stmt, "")
+
+	case *stmt.While:
+		p.write("while (")
+		p.print(n.Cond)
+		p.write(")")
+		p.printBody(n.Stmt)
+	case *stmt.AltWhile:
+		p.write("while (")
+		p.print(n.Cond)
+		p.write(")")
+		p.printAltBody(n.Stmt, "endwhile;")
+	case *stmt.Do:
+		p.write("do")
+		if p.printBody(n.Stmt) {
+			p.write(" ")
+		} else {
+			p.newline()
+		}
+		p.write("while (")
+		p.print(n.Cond)
+		p.write(");")
+	case *stmt.For:
+		p.printForHeader(n.Init, n.Cond, n.Loop)
+		p.printBody(n.Stmt)
+	case *stmt.AltFor:
+		p.printForHeader(n.Init, n.Cond, n.Loop)
+		p.printAltBody(n.Stmt, "endfor;")
+	case *stmt.Foreach:
+		p.printForeachHeader(n.Expr, n.Key, n.Variable, n.ByRef)
+		p.printBody(n.Stmt)
+	case *stmt.AltForeach:
+		p.printForeachHeader(n.Expr, n.Key, n.Variable, n.ByRef)
+		p.printAltBody(n.Stmt, "endforeach;")
+	case *stmt.Switch:
+		p.write("switch (")
+		p.print(n.Cond)
+		p.write(") ")
+		p.printBlock(n.Cases)
+	case *stmt.AltSwitch:
+		p.write("switch (")
+		p.print(n.Cond)
+		p.write(")")
+		p.printAltBody(&stmt.StmtList{Stmts: n.Cases}, "endswitch;")
+	case *stmt.Case:
+		p.write("case ")
+		p.print(n.Cond)
+		p.printAltBody(&stmt.StmtList{Stmts: n.Stmts}, "")
+	case *stmt.Default:
+		p.write("default")
+		p.printAltBody(&stmt.StmtList{Stmts: n.Stmts}, "")
+	case *stmt.Declare:
+		p.write("declare(")
+		p.printList(", ", n.Consts)
+		p.write(")")
+		p.printBody(n.Stmt)
+
+	case *stmt.Try:
+		p.write("try ")
+		p.printBlock(n.Stmts)
+		for _, c := range n.Catches {
+			p.write(" ")
+			p.print(c)
+		}
+		if n.Finally != nil {
+			p.write(" ")
+			p.print(n.Finally)
+		}
+	case *stmt.Catch:
+		p.write("catch (")
+		p.printList("|", n.Types)
+		p.write(" ")
+		p.print(n.Variable)
+		p.write(") ")
+		p.printBlock(n.Stmts)
+	case *stmt.Finally:
+		p.write("finally ")
+		p.printBlock(n.Stmts)
+
+	case *stmt.Function:
+		p.write("function ")
+		if n.ReturnsRef {
+			p.write("&")
+		}
+		p.print(n.FunctionName)
+		p.write("(")
+		p.printList(", ", n.Params)
+		p.write(")")
+		p.printReturnType(n.ReturnType)
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *stmt.Class:
+		p.printClass("class", n)
+	case *stmt.Interface:
+		p.write("interface ")
+		p.print(n.InterfaceName)
+		if len(n.Extends) != 0 {
+			p.write(" extends ")
+			p.printList(", ", n.Extends)
+		}
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *stmt.Trait:
+		p.write("trait ")
+		p.print(n.TraitName)
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *stmt.ClassMethod:
+		if len(n.Modifiers) != 0 {
+			p.printList(" ", n.Modifiers)
+			p.write(" ")
+		}
+		p.write("function ")
+		if n.ReturnsRef {
+			p.write("&")
+		}
+		p.print(n.MethodName)
+		p.write("(")
+		p.printList(", ", n.Params)
+		p.write(")")
+		p.printReturnType(n.ReturnType)
+		if n.Stmts == nil {
+			// Abstract and interface methods.
+			p.write(";")
+			return
+		}
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *stmt.ClassConstList:
+		if len(n.Modifiers) != 0 {
+			p.printList(" ", n.Modifiers)
+			p.write(" ")
+		}
+		p.write("const ")
+		p.printList(", ", n.Consts)
+		p.write(";")
+	case *stmt.ConstList:
+		p.write("const ")
+		p.printList(", ", n.Consts)
+		p.write(";")
+	case *stmt.Constant:
+		p.print(n.ConstantName)
+		p.write(" = ")
+		p.print(n.Expr)
+	case *stmt.PropertyList:
+		p.printList(" ", n.Modifiers)
+		p.write(" ")
+		p.printList(", ", n.Properties)
+		p.write(";")
+	case *stmt.Property:
+		p.print(n.Variable)
+		if n.Expr != nil {
+			p.write(" = ")
+			p.print(n.Expr)
+		}
+	case *stmt.TraitUse:
+		p.write("use ")
+		p.printList(", ", n.Traits)
+		if len(n.Adaptations) == 0 {
+			p.write(";")
+			return
+		}
+		p.write(" ")
+		p.printBlock(n.Adaptations)
+	case *stmt.TraitUseAlias:
+		p.print(n.Ref)
+		p.write(" as")
+		if n.Modifier != nil {
+			p.write(" ")
+			p.print(n.Modifier)
+		}
+		if n.Alias != nil {
+			p.write(" ")
+			p.print(n.Alias)
+		}
+		p.write(";")
+	case *stmt.TraitUsePrecedence:
+		p.print(n.Ref)
+		p.write(" insteadof ")
+		p.printList(", ", n.Insteadof)
+		p.write(";")
+	case *stmt.TraitMethodRef:
+		if n.Trait != nil {
+			p.print(n.Trait)
+			p.write("::")
+		}
+		p.print(n.Method)
+
+	case *stmt.Namespace:
+		p.write("namespace")
+		if n.NamespaceName != nil {
+			p.write(" ")
+			p.print(n.NamespaceName)
+		}
+		if n.Stmts == nil {
+			p.write(";")
+			return
+		}
+		p.write(" ")
+		p.printBlock(n.Stmts)
+	case *stmt.UseList:
+		p.write("use ")
+		if n.UseType != nil {
+			p.print(n.UseType)
+			p.write(" ")
+		}
+		p.printList(", ", n.Uses)
+		p.write(";")
+	case *stmt.GroupUse:
+		p.write("use ")
+		if n.UseType != nil {
+			p.print(n.UseType)
+			p.write(" ")
+		}
+		p.print(n.Prefix)
+		p.write(`\{`)
+		p.printList(", ", n.UseList)
+		p.write("};")
+	case *stmt.Use:
+		if n.UseType != nil {
+			p.print(n.UseType)
+			p.write(" ")
+		}
+		p.print(n.Use)
+		if n.Alias != nil {
+			p.write(" as ")
+			p.print(n.Alias)
+		}
+	}
+}
+
+// printKeywordStmt prints the statement like "return" with optional argument.
+func (p *printer) printKeywordStmt(keyword string, arg node.Node) {
+	p.write(keyword)
+	if arg != nil {
+		p.write(" ")
+		p.print(arg)
+	}
+	p.write(";")
+}
+
+// printElse prints the separator before the else or elseif branch.
+func (p *printer) printElse(braced bool) {
+	if braced {
+		p.write(" ")
+	} else {
+		p.newline()
+	}
+}
+
+func (p *printer) printElseIf(n node.Node) bool {
+	elseif := n.(*stmt.ElseIf)
+	p.write("elseif (")
+	p.print(elseif.Cond)
+	p.write(")")
+	return p.printBody(elseif.Stmt)
+}
+
+func (p *printer) printForHeader(init, cond, loop []node.Node) {
+	p.write("for (")
+	p.printList(", ", init)
+	p.write(";")
+	if len(cond) != 0 {
+		p.write(" ")
+		p.printList(", ", cond)
+	}
+	p.write(";")
+	if len(loop) != 0 {
+		p.write(" ")
+		p.printList(", ", loop)
+	}
+	p.write(")")
+}
+
+func (p *printer) printForeachHeader(x, key, val node.Node, byRef bool) {
+	p.write("foreach (")
+	p.print(x)
+	p.write(" as ")
+	if key != nil {
+		p.print(key)
+		p.write(" => ")
+	}
+	if byRef {
+		p.write("&")
+	}
+	p.print(val)
+	p.write(")")
+}
@@ -0,0 +1,281 @@
+package astprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quasilyte/php-critic/internal/astcmp"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/php7"
+)
+
+func parseExpr(t *testing.T, code string) node.Node {
+	return parseStmt(t, code+";").(*stmt.Expression).Expr
+}
+
+func parseStmt(t *testing.T, code string) node.Node {
+	p := php7.NewParser(strings.NewReader("<?php "+code), "test.php")
+	p.Parse()
+	if errs := p.GetErrors(); len(errs) != 0 {
+		t.Fatalf("parse %q: %v", code, errs[0])
+	}
+	return p.GetRootNode().(*stmt.StmtList).Stmts[0]
+}
+
+func TestPrintExpr(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`$a`, `$a`},
+		{`$$a`, `$$a`},
+		{`${'a' . $b}`, `${'a' . $b}`},
+		{`$a  +$b`, `$a + $b`},
+		{`10`, `10`},
+		{`1.5`, `1.5`},
+		{`'a'`, `'a'`},
+		{`"a\n"`, `"a\n"`},
+		{`"a $b {$c->d} ${e}"`, `"a {$b} {$c->d} {$e}"`},
+		{"`ls $dir`", "`ls {$dir}`"},
+		{`__LINE__`, `__LINE__`},
+		{`PHP_EOL`, `PHP_EOL`},
+		{`\A\B::C`, `\A\B::C`},
+		{`namespace\f()`, `namespace\f()`},
+		{`A::$x`, `A::$x`},
+		{`A::f(1)`, `A::f(1)`},
+		{`A::$f()`, `A::$f()`},
+		{`$a->b->c()`, `$a->b->c()`},
+		{`$a->$b`, `$a->$b`},
+		{`$a->{'b' . $c}`, `$a->{'b' . $c}`},
+		{`$a[0][$b]`, `$a[0][$b]`},
+		{`$a[]`, `$a[]`},
+		{`'abc'[0]`, `'abc'[0]`},
+		{`f($a, ...$b)`, `f($a, ...$b)`},
+		{`(function() {})()`, `(function () {})()`},
+		{`($a ?: $b)->c`, `($a ?: $b)->c`},
+		{`(new A)->f()`, `(new A)->f()`},
+		{`new A()`, `new A()`},
+		{`new A`, `new A`},
+		{`new $a[0]`, `new $a[0]`},
+		{`new class(1) extends A {}`, `new class(1) extends A {}`},
+		{`clone $a`, `clone $a`},
+		{`[1, 'a' => &$b]`, `[1, 'a' => &$b]`},
+		{`array(1, 2)`, `array(1, 2)`},
+		{`list($a, , $b) = $c`, `list($a, , $b) = $c`},
+		{`[$a, [$b]] = $c`, `[$a, [$b]] = $c`},
+		{`isset($a, $b[0])`, `isset($a, $b[0])`},
+		{`empty($a)`, `empty($a)`},
+		{`exit`, `exit`},
+		{`die('x')`, `die('x')`},
+		{`@f()`, `@f()`},
+		{`(int)$a`, `(int)$a`},
+		{`(string)($a . $b)`, `(string)($a . $b)`},
+		{`-$a`, `-$a`},
+		{`-(-$a)`, `-(-$a)`},
+		{`-(--$a)`, `-(--$a)`},
+		{`+(+$a)`, `+(+$a)`},
+		{`-$a ** 2`, `-$a ** 2`},
+		{`(-$a) ** 2`, `(-$a) ** 2`},
+		{`$a++ + ++$b`, `$a++ + ++$b`},
+		{`!$a instanceof A`, `!$a instanceof A`},
+		{`!($a && $b)`, `!($a && $b)`},
+		{`$a = $b += 1`, `$a = $b += 1`},
+		{`$a =& $b`, `$a =& $b`},
+		{`$a ?? $b ?? $c`, `$a ?? $b ?? $c`},
+		{`($a ?? $b) ?? $c`, `($a ?? $b) ?? $c`},
+		{`$a ** $b ** $c`, `$a ** $b ** $c`},
+		{`($a ** $b) ** $c`, `($a ** $b) ** $c`},
+		{`$a - ($b - $c)`, `$a - ($b - $c)`},
+		{`$a - $b - $c`, `$a - $b - $c`},
+		{`($a + $b) * $c`, `($a + $b) * $c`},
+		{`$a . $b . $c`, `$a . $b . $c`},
+		{`$a . $b + $c`, `($a . $b) + $c`},
+		{`$a + $b . $c`, `($a + $b) . $c`},
+		{`$a . ($b - $c)`, `$a . ($b - $c)`},
+		{`$a && $b || $c and $d`, `$a && $b || $c and $d`},
+		{`$a && ($b || $c)`, `$a && ($b || $c)`},
+		{`$a == ($b == $c)`, `$a == ($b == $c)`},
+		{`$a < $b == $c`, `$a < $b == $c`},
+		{`$a ? $b : ($c ? $d : $e)`, `$a ? $b : ($c ? $d : $e)`},
+		{`$a ? $b = 1 : $c`, `$a ? $b = 1 : $c`},
+		{`$a ?: $b`, `$a ?: $b`},
+		{`$a = $b ? 1 : 2`, `$a = $b ? 1 : 2`},
+		{`($a = $b) ? 1 : 2`, `($a = $b) ? 1 : 2`},
+		{`!$a = f()`, `!($a = f())`},
+		{`$a and $b = 1`, `$a and $b = 1`},
+		{`print $a . 'x'`, `print $a . 'x'`},
+		{`yield`, `yield`},
+		{`yield $a => $b`, `yield $a => $b`},
+		{`yield from f()`, `yield from f()`},
+		{`include 'a.php'`, `include 'a.php'`},
+		{`static function &($a, &$b = 1) use ($c, &$d): ?int { return $a; }`,
+			`static function &($a, &$b = 1) use ($c, &$d): ?int {
+    return $a;
+}`},
+	}
+
+	for _, test := range tests {
+		n := parseExpr(t, test.code)
+		have := Print(n)
+		if have != test.want {
+			t.Errorf("print %q:\nhave: %s\nwant: %s", test.code, have, test.want)
+			continue
+		}
+		if !astcmp.Equal(parseExpr(t, have), n) {
+			t.Errorf("print %q: %q parses to a different AST", test.code, have)
+		}
+	}
+}
+
+func TestPrintStmt(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`echo $a, 'b';`, `echo $a, 'b';`},
+		{`return;`, `return;`},
+		{`global $a, $b;`, `global $a, $b;`},
+		{`static $a = 1, $b;`, `static $a = 1, $b;`},
+		{`unset($a[0]);`, `unset($a[0]);`},
+		{`if ($a) f(); elseif ($b) { g(); } else h();`, `if ($a)
+    f();
+elseif ($b) {
+    g();
+} else
+    h();`},
+		{`if ($a): f(); else: g(); endif;`, `if ($a):
+    f();
+else:
+    g();
+endif;`},
+		{`while ($a) {}`, `while ($a) {}`},
+		{`while ($a);`, `while ($a);`},
+		{`do { f(); } while ($a);`, `do {
+    f();
+} while ($a);`},
+		{`for ($i = 0, $j = 1; $i < 10; $i++) continue 2;`, `for ($i = 0, $j = 1; $i < 10; $i++)
+    continue 2;`},
+		{`for (;;) {}`, `for (;;) {}`},
+		{`foreach ($a as $k => &$v) { break; }`, `foreach ($a as $k => &$v) {
+    break;
+}`},
+		{`foreach ($a as list($b, $c)): endforeach;`, `foreach ($a as list($b, $c)):
+endforeach;`},
+		{`switch ($a) { case 1: case 2: f(); break; default: g(); }`, `switch ($a) {
+    case 1:
+    case 2:
+        f();
+        break;
+    default:
+        g();
+}`},
+		{`try { f(); } catch (A|B $e) { g(); } finally {}`, `try {
+    f();
+} catch (A|B $e) {
+    g();
+} finally {}`},
+		{`function &f(int $a, ...$b): void {}`, `function &f(int $a, ...$b): void {}`},
+		{`abstract class A extends B implements C, D { const X = 1; private static $a = [], $b; abstract protected function f(); public function g() { return $this->a; } }`,
+			`abstract class A extends B implements C, D {
+    const X = 1;
+    private static $a = [], $b;
+    abstract protected function f();
+    public function g() {
+        return $this->a;
+    }
+}`},
+		{`interface A extends B, C { function f(); }`, `interface A extends B, C {
+    function f();
+}`},
+		{`trait T { use A, B { A::f insteadof B; g as protected h; } }`, `trait T {
+    use A, B {
+        A::f insteadof B;
+        g as protected h;
+    }
+}`},
+		{`namespace A\B;`, `namespace A\B;`},
+		{`namespace A { const X = 1; }`, `namespace A {
+    const X = 1;
+}`},
+		{`use A\B as C, D;`, `use A\B as C, D;`},
+		{`use function A\f;`, `use function A\f;`},
+		{`use A\{B, function c};`, `use A\{B, function c};`},
+		{`declare(strict_types=1);`, `declare(strict_types = 1);`},
+		{`throw new E('x');`, `throw new E('x');`},
+		{`goto a;`, `goto a;`},
+	}
+
+	for _, test := range tests {
+		n := parseStmt(t, test.code)
+		have := Print(n)
+		if have != test.want {
+			t.Errorf("print %q:\nhave:\n%s\nwant:\n%s", test.code, have, test.want)
+			continue
+		}
+		if !astcmp.Equal(parseStmt(t, have), n) {
+			t.Errorf("print %q: %q parses to a different AST", test.code, have)
+		}
+	}
+}
+
+func TestPrintParens(t *testing.T) {
+	v := func(name string) node.Node {
+		return &expr.Variable{VarName: &node.Identifier{Value: name}}
+	}
+
+	// The parser drops the parens, so the nodes are built by hand.
+	tests := []struct {
+		n    node.Node
+		want string
+	}{
+		{&binary.Mul{Left: &binary.Plus{Left: v("a"), Right: v("b")}, Right: v("c")}, `($a + $b) * $c`},
+		{&binary.Minus{Left: v("a"), Right: &binary.Minus{Left: v("b"), Right: v("c")}}, `$a - ($b - $c)`},
+		{&binary.Concat{Left: v("a"), Right: &binary.Plus{Left: v("b"), Right: v("c")}}, `$a . ($b + $c)`},
+		{&binary.Minus{Left: &binary.Concat{Left: v("a"), Right: v("b")}, Right: v("c")}, `($a . $b) - $c`},
+		{&binary.Greater{Left: &expr.Ternary{Condition: v("a"), IfFalse: v("b")}, Right: v("c")}, `($a ?: $b) > $c`},
+		{&expr.Ternary{Condition: &expr.Ternary{Condition: v("a"), IfFalse: v("b")}, IfFalse: v("c")}, `($a ?: $b) ?: $c`},
+		{&expr.PropertyFetch{Variable: &binary.Concat{Left: v("a"), Right: v("b")}, Property: &node.Identifier{Value: "c"}}, `($a . $b)->c`},
+		{&expr.UnaryMinus{Expr: &expr.UnaryMinus{Expr: v("a")}}, `-(-$a)`},
+		{&expr.BooleanNot{Expr: &expr.InstanceOf{Expr: v("a"), Class: v("b")}}, `!$a instanceof $b`},
+		{&expr.InstanceOf{Expr: &expr.BooleanNot{Expr: v("a")}, Class: v("b")}, `(!$a) instanceof $b`},
+	}
+
+	for _, test := range tests {
+		have := Print(test.n)
+		if have != test.want {
+			t.Errorf("print %T: have %q, want %q", test.n, have, test.want)
+			continue
+		}
+		if !astcmp.Equal(parseExpr(t, have), test.n) {
+			t.Errorf("print %T: %q parses to a different AST", test.n, have)
+		}
+	}
+}
+
+func TestMaxLen(t *testing.T) {
+	tests := []struct {
+		code   string
+		maxLen int
+		want   string
+	}{
+		{`f($a, $b)`, 0, `f($a, $b)`},
+		{`f($a, $b)`, 9, `f($a, $b)`},
+		{`f($a, $b)`, 8, `f($a, $b...`},
+		{`f($a, $b)`, 3, `f($...`},
+		{`'привет'`, 4, `'п...`},
+		{`'привет'`, 5, `'пр...`},
+		{`function() { return 1; }`, 16, `function () {
+  ...`},
+	}
+
+	for _, test := range tests {
+		cfg := Config{MaxLen: test.maxLen, Indent: "  "}
+		have := cfg.Print(parseExpr(t, test.code))
+		if have != test.want {
+			t.Errorf("print %q with MaxLen=%d:\nhave: %q\nwant: %q", test.code, test.maxLen, have, test.want)
+		}
+	}
+}
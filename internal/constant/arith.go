@@ -0,0 +1,141 @@
+package constant
+
+import (
+	"math"
+)
+
+// arith implements arithmetic binary operations over the numeric operands.
+//
+// intOp is used when both operands are ints; if it reports an overflow,
+// the operation is performed over floats, like PHP does.
+func arith(x, y Value, intOp func(a, b IntValue) (IntValue, bool), floatOp func(a, b float64) float64) Value {
+	v1, ok1 := toNumber(x)
+	v2, ok2 := toNumber(y)
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	a, ok1 := v1.(IntValue)
+	b, ok2 := v2.(IntValue)
+	if ok1 && ok2 {
+		if res, ok := intOp(a, b); ok {
+			return res
+		}
+	}
+	return FloatValue(floatOp(toFloat64(v1), toFloat64(v2)))
+}
+
+func addInts(a, b IntValue) (IntValue, bool) {
+	res := a + b
+	if (a > 0 && b > 0 && res < 0) || (a < 0 && b < 0 && res >= 0) {
+		return 0, false
+	}
+	return res, true
+}
+
+func subInts(a, b IntValue) (IntValue, bool) {
+	res := a - b
+	if (a >= 0 && b < 0 && res < 0) || (a < 0 && b > 0 && res >= 0) {
+		return 0, false
+	}
+	return res, true
+}
+
+func mulInts(a, b IntValue) (IntValue, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	res := a * b
+	if res/b != a {
+		return 0, false
+	}
+	return res, true
+}
+
+// powInts computes base**exp for non-negative exp.
+func powInts(base, exp IntValue) (IntValue, bool) {
+	res := IntValue(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 != 0 {
+			res, ok = mulInts(res, base)
+			if !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			base, ok = mulInts(base, base)
+			if !ok {
+				return 0, false
+			}
+		}
+	}
+	return res, true
+}
+
+// bitwiseStrings applies op to every pair of bytes from x and y.
+// Result length is equal to the shortest operand length.
+func bitwiseStrings(x, y StringValue, op func(a, b byte) byte) StringValue {
+	if len(x) > len(y) {
+		x, y = y, x
+	}
+	res := make([]byte, len(x))
+	for i := range res {
+		res[i] = op(x[i], y[i])
+	}
+	return StringValue(res)
+}
+
+// toNumber converts x to int or float operand of arithmetic operation.
+//
+// Strings that have leading numeric part are converted to that number.
+// Non-numeric strings are 0 in PHP 7 and a TypeError in PHP 8.
+// Arrays can't be used as arithmetic operands.
+func toNumber(x Value) (Value, bool) {
+	switch x := x.(type) {
+	case IntValue, FloatValue:
+		return x, true
+	case BoolValue:
+		if x {
+			return IntValue(1), true
+		}
+		return IntValue(0), true
+	case NullValue:
+		return IntValue(0), true
+	case StringValue:
+		v, _ := scanNumber(string(x))
+		if v != nil {
+			return v, true
+		}
+		if TargetVersion >= PHP8 {
+			return nil, false
+		}
+		return IntValue(0), true
+	}
+	return nil, false
+}
+
+// toIntOperand converts x to int operand of integer-only operation,
+// like "%" or "<<".
+func toIntOperand(x Value) (IntValue, bool) {
+	v, ok := toNumber(x)
+	if !ok {
+		return 0, false
+	}
+	if f, ok := v.(FloatValue); ok {
+		return floatToInt(float64(f))
+	}
+	return v.(IntValue), true
+}
+
+// floatToInt truncates f to int.
+// Non-finite and out of range values can't be converted reliably.
+func floatToInt(f float64) (IntValue, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, false
+	}
+	return IntValue(f), true
+}
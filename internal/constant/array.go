@@ -0,0 +1,216 @@
+package constant
+
+import (
+	"math"
+	"strconv"
+)
+
+// ArrayValue is such an x value that is_array($x) returns true.
+//
+// It's an ordered map that preserves elements insertion order.
+// Keys are always normalized to IntValue or StringValue.
+//
+// ArrayValue should not be modified after it was constructed.
+type ArrayValue struct {
+	keys      []Value
+	values    []Value
+	index     map[Value]int
+	nextIndex IntValue
+}
+
+// NewArray returns an empty array constant.
+func NewArray() *ArrayValue {
+	return &ArrayValue{index: make(map[Value]int)}
+}
+
+// ToArray converts x constant to array constant following PHP conversion rules.
+// Second bool result tells whether that conversion was successful.
+func ToArray(x Value) (*ArrayValue, bool) {
+	switch x := x.(type) {
+	case *ArrayValue:
+		return x, true
+	case NullValue:
+		return NewArray(), true
+	case IntValue, FloatValue, StringValue, BoolValue:
+		arr := NewArray()
+		arr.Append(x)
+		return arr, true
+	}
+	return nil, false
+}
+
+// NormalizeKey converts x to a valid array key following PHP rules.
+//
+// Decimal integer strings are converted to ints,
+// floats are truncated, bools become 0 and 1, null becomes "".
+// Second bool result tells whether x can be used as a key.
+func NormalizeKey(x Value) (Value, bool) {
+	switch x := x.(type) {
+	case IntValue:
+		return x, true
+	case StringValue:
+		if v, ok := decimalIntKey(string(x)); ok {
+			return v, true
+		}
+		return x, true
+	case FloatValue:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return nil, false
+		}
+		return IntValue(x), true
+	case BoolValue:
+		if x {
+			return IntValue(1), true
+		}
+		return IntValue(0), true
+	case NullValue:
+		return StringValue(""), true
+	}
+	return nil, false
+}
+
+// decimalIntKey reports whether s is a canonical decimal int
+// representation, like "10" or "-5" (but not "010", "+5" or "-0").
+func decimalIntKey(s string) (IntValue, bool) {
+	if s == "" || len(s) > 20 {
+		return 0, false
+	}
+	digits := s
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" || (digits[0] == '0' && len(s) != 1) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if !isDigit(digits[i]) {
+			return 0, false
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return IntValue(v), true
+}
+
+// Len returns the number of array elements.
+func (a *ArrayValue) Len() int { return len(a.keys) }
+
+// Get returns the array element associated with the key.
+// Key is normalized before the lookup.
+func (a *ArrayValue) Get(key Value) (Value, bool) {
+	k, ok := NormalizeKey(key)
+	if !ok {
+		return nil, false
+	}
+	i, ok := a.index[k]
+	if !ok {
+		return nil, false
+	}
+	return a.values[i], true
+}
+
+// Set binds v to the specified key.
+// Returns false if key can't be used as an array key.
+func (a *ArrayValue) Set(key, v Value) bool {
+	k, ok := NormalizeKey(key)
+	if !ok {
+		return false
+	}
+	if i, ok := a.index[k]; ok {
+		a.values[i] = v
+		return true
+	}
+	a.index[k] = len(a.keys)
+	a.keys = append(a.keys, k)
+	a.values = append(a.values, v)
+	if k, ok := k.(IntValue); ok && k >= a.nextIndex {
+		a.nextIndex = k + 1
+	}
+	return true
+}
+
+// Append adds v using the next free int key, like "$a[] = v" does.
+func (a *ArrayValue) Append(v Value) {
+	a.Set(a.nextIndex, v)
+}
+
+// Iterate calls visit for every array element in the insertion order.
+func (a *ArrayValue) Iterate(visit func(k, v Value)) {
+	for i, k := range a.keys {
+		visit(k, a.values[i])
+	}
+}
+
+// Contains reports whether array contains x.
+// If strict is true, "===" is used for comparison, "==" otherwise.
+// Returns UnknownValue if some comparison can't be performed.
+func (a *ArrayValue) Contains(x Value, strict bool) Value {
+	cmp := Equal
+	if strict {
+		cmp = Identical
+	}
+	for _, v := range a.values {
+		res, ok := cmp(x, v).(BoolValue)
+		if !ok {
+			return UnknownValue{}
+		}
+		if res {
+			return BoolValue(true)
+		}
+	}
+	return BoolValue(false)
+}
+
+func (a *ArrayValue) isValid() bool { return true }
+
+func identicalArrays(x, y *ArrayValue) Value {
+	if x.Len() != y.Len() {
+		return BoolValue(false)
+	}
+	for i, k := range x.keys {
+		if y.keys[i] != k {
+			return BoolValue(false)
+		}
+		res := Identical(x.values[i], y.values[i])
+		if res, ok := res.(BoolValue); ok && bool(res) {
+			continue
+		}
+		return res
+	}
+	return BoolValue(true)
+}
+
+// compareArrays compares arrays by their size and then element by element.
+func compareArrays(x, y *ArrayValue) (int, bool) {
+	switch {
+	case x.Len() < y.Len():
+		return -1, true
+	case x.Len() > y.Len():
+		return 1, true
+	}
+	for i, k := range x.keys {
+		v2, ok := y.Get(k)
+		if !ok {
+			return cmpUnordered, true
+		}
+		res, ok := compare(x.values[i], v2)
+		if !ok || res != 0 {
+			return res, ok
+		}
+	}
+	return 0, true
+}
+
+// unionArrays implements "+" operator for arrays.
+func unionArrays(x, y *ArrayValue) *ArrayValue {
+	res := NewArray()
+	x.Iterate(func(k, v Value) { res.Set(k, v) })
+	y.Iterate(func(k, v Value) {
+		if _, ok := res.Get(k); !ok {
+			res.Set(k, v)
+		}
+	})
+	return res
+}
@@ -1,24 +1,44 @@
 package constant
 
+import (
+	"math"
+)
+
 // BinaryOps is a mapping of operators to the functions that implement tham.
 var BinaryOps = map[string]func(Value, Value) Value{
 	"||":  Or,
 	"or":  Or,
 	"&&":  And,
 	"and": And,
+	"xor": Xor,
 
 	".": Concat,
 
-	"+": Add,
-	"-": Sub,
+	"+":  Add,
+	"-":  Sub,
+	"*":  Mul,
+	"/":  Div,
+	"%":  Mod,
+	"**": Pow,
+
+	"|":  BitOr,
+	"&":  BitAnd,
+	"^":  BitXor,
+	"<<": ShiftLeft,
+	">>": ShiftRight,
 
-	"|": BitOr,
-	"&": BitAnd,
+	"??": Coalesce,
 
 	"===": Identical,
+	"!==": NotIdentical,
 	"==":  Equal,
+	"!=":  NotEqual,
+	"<>":  NotEqual,
 	">":   GreaterThan,
+	">=":  GreaterOrEqual,
 	"<":   LessThan,
+	"<=":  LessOrEqual,
+	"<=>": Spaceship,
 }
 
 // Concat performs string "." operation.
@@ -65,49 +85,104 @@ func And(x, y Value) Value {
 	}
 }
 
-// Sub performs arithmetic "-".
-func Sub(x, y Value) Value {
-	switch x := x.(type) {
-	case IntValue:
-		y, ok := y.(IntValue)
-		if ok {
-			return x - y
-		}
-	case FloatValue:
-		y, ok := y.(FloatValue)
-		if ok {
-			return x - y
-		}
+// Xor performs logical "xor".
+func Xor(x, y Value) Value {
+	v1, ok1 := ToBool(x)
+	v2, ok2 := ToBool(y)
+	if ok1 && ok2 {
+		return BoolValue(v1 != v2)
 	}
 	return UnknownValue{}
 }
 
 // Add performs arithmetic "+".
+// For arrays, it performs a union operation.
 func Add(x, y Value) Value {
-	switch x := x.(type) {
-	case IntValue:
-		y, ok := y.(IntValue)
-		if ok {
-			return x + y
-		}
-	case FloatValue:
-		y, ok := y.(FloatValue)
-		if ok {
-			return x + y
+	if x, ok := x.(*ArrayValue); ok {
+		if y, ok := y.(*ArrayValue); ok {
+			return unionArrays(x, y)
 		}
-	case StringValue:
-		y, ok := y.(StringValue)
-		if ok {
-			return x + y
+	}
+	return arith(x, y, addInts, func(a, b float64) float64 { return a + b })
+}
+
+// Sub performs arithmetic "-".
+func Sub(x, y Value) Value {
+	return arith(x, y, subInts, func(a, b float64) float64 { return a - b })
+}
+
+// Mul performs arithmetic "*".
+func Mul(x, y Value) Value {
+	return arith(x, y, mulInts, func(a, b float64) float64 { return a * b })
+}
+
+// Div performs arithmetic "/".
+//
+// Division by zero results in UnknownValue as it's an error.
+func Div(x, y Value) Value {
+	v1, ok1 := toNumber(x)
+	v2, ok2 := toNumber(y)
+	if !ok1 || !ok2 || toFloat64(v2) == 0 {
+		return UnknownValue{}
+	}
+	a, ok1 := v1.(IntValue)
+	b, ok2 := v2.(IntValue)
+	if ok1 && ok2 && a%b == 0 && !(a == math.MinInt64 && b == -1) {
+		return a / b
+	}
+	return FloatValue(toFloat64(v1) / toFloat64(v2))
+}
+
+// Mod performs arithmetic "%".
+//
+// Both operands are converted to int before the operation.
+// Modulo by zero results in UnknownValue as it's an error.
+func Mod(x, y Value) Value {
+	a, ok1 := toIntOperand(x)
+	b, ok2 := toIntOperand(y)
+	if !ok1 || !ok2 || b == 0 {
+		return UnknownValue{}
+	}
+	if b == -1 {
+		// Avoids MinInt64 % -1 overflow.
+		return IntValue(0)
+	}
+	return a % b
+}
+
+// Pow performs arithmetic "**".
+func Pow(x, y Value) Value {
+	v1, ok1 := toNumber(x)
+	v2, ok2 := toNumber(y)
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	base, ok1 := v1.(IntValue)
+	exp, ok2 := v2.(IntValue)
+	if ok1 && ok2 && exp >= 0 {
+		if res, ok := powInts(base, exp); ok {
+			return res
 		}
 	}
-	return UnknownValue{}
+	return FloatValue(math.Pow(toFloat64(v1), toFloat64(v2)))
 }
 
 // BitOr performs bitwise "|".
 func BitOr(x, y Value) Value {
-	v1, ok1 := ToInt(x)
-	v2, ok2 := ToInt(y)
+	if x, ok := x.(StringValue); ok {
+		if y, ok := y.(StringValue); ok {
+			if len(x) < len(y) {
+				x, y = y, x
+			}
+			res := []byte(x)
+			for i := 0; i < len(y); i++ {
+				res[i] |= y[i]
+			}
+			return StringValue(res)
+		}
+	}
+	v1, ok1 := toIntOperand(x)
+	v2, ok2 := toIntOperand(y)
 	if ok1 && ok2 {
 		return v1 | v2
 	}
@@ -116,14 +191,70 @@ func BitOr(x, y Value) Value {
 
 // BitAnd performs bitwise "&".
 func BitAnd(x, y Value) Value {
-	v1, ok1 := ToInt(x)
-	v2, ok2 := ToInt(y)
+	if x, ok := x.(StringValue); ok {
+		if y, ok := y.(StringValue); ok {
+			return bitwiseStrings(x, y, func(a, b byte) byte { return a & b })
+		}
+	}
+	v1, ok1 := toIntOperand(x)
+	v2, ok2 := toIntOperand(y)
 	if ok1 && ok2 {
 		return v1 & v2
 	}
 	return UnknownValue{}
 }
 
+// BitXor performs bitwise "^".
+func BitXor(x, y Value) Value {
+	if x, ok := x.(StringValue); ok {
+		if y, ok := y.(StringValue); ok {
+			return bitwiseStrings(x, y, func(a, b byte) byte { return a ^ b })
+		}
+	}
+	v1, ok1 := toIntOperand(x)
+	v2, ok2 := toIntOperand(y)
+	if ok1 && ok2 {
+		return v1 ^ v2
+	}
+	return UnknownValue{}
+}
+
+// ShiftLeft performs bitwise "<<".
+//
+// Shift by negative number results in UnknownValue as it's an error.
+func ShiftLeft(x, y Value) Value {
+	v, ok1 := toIntOperand(x)
+	n, ok2 := toIntOperand(y)
+	if !ok1 || !ok2 || n < 0 {
+		return UnknownValue{}
+	}
+	return v << uint64(n)
+}
+
+// ShiftRight performs bitwise ">>".
+//
+// Shift by negative number results in UnknownValue as it's an error.
+func ShiftRight(x, y Value) Value {
+	v, ok1 := toIntOperand(x)
+	n, ok2 := toIntOperand(y)
+	if !ok1 || !ok2 || n < 0 {
+		return UnknownValue{}
+	}
+	return v >> uint64(n)
+}
+
+// Coalesce performs null-coalescing "??" operation.
+func Coalesce(x, y Value) Value {
+	switch x.(type) {
+	case UnknownValue:
+		return UnknownValue{}
+	case NullValue:
+		return y
+	default:
+		return x
+	}
+}
+
 // Identical performs "===" comparison.
 func Identical(x, y Value) Value {
 	switch x := x.(type) {
@@ -142,58 +273,86 @@ func Identical(x, y Value) Value {
 		if ok {
 			return BoolValue(x == y)
 		}
+	case BoolValue:
+		y, ok := y.(BoolValue)
+		if ok {
+			return BoolValue(x == y)
+		}
+	case NullValue:
+		_, ok := y.(NullValue)
+		if ok {
+			return BoolValue(true)
+		}
+	case *ArrayValue:
+		y, ok := y.(*ArrayValue)
+		if ok {
+			return identicalArrays(x, y)
+		}
+	}
+	if x.isValid() && y.isValid() {
+		// Values of different types are never identical.
+		return BoolValue(false)
 	}
 	return UnknownValue{}
 }
 
+// NotIdentical performs "!==" comparison.
+func NotIdentical(x, y Value) Value {
+	return Not(Identical(x, y))
+}
+
 // Equal performs "==" comparison.
 func Equal(x, y Value) Value {
-	// TODO(quasilyte): support non-strict forms of comparison?
-	return Identical(x, y)
+	res, ok := compare(x, y)
+	if !ok {
+		return UnknownValue{}
+	}
+	return BoolValue(res == 0)
+}
+
+// NotEqual performs "!=" comparison.
+// Also works for "<>" operator.
+func NotEqual(x, y Value) Value {
+	return Not(Equal(x, y))
 }
 
 // GreaterThan performs ">" comparison.
 func GreaterThan(x, y Value) Value {
-	// TODO(quasilyte): support non-strict forms of comparison?
-	switch x := x.(type) {
-	case IntValue:
-		y, ok := y.(IntValue)
-		if ok {
-			return BoolValue(x > y)
-		}
-	case FloatValue:
-		y, ok := y.(FloatValue)
-		if ok {
-			return BoolValue(x > y)
-		}
-	case StringValue:
-		y, ok := y.(StringValue)
-		if ok {
-			return BoolValue(x > y)
-		}
-	}
-	return UnknownValue{}
+	// PHP evaluates "x > y" as "y < x".
+	return LessThan(y, x)
+}
+
+// GreaterOrEqual performs ">=" comparison.
+func GreaterOrEqual(x, y Value) Value {
+	return LessOrEqual(y, x)
 }
 
 // LessThan performs "<" comparison.
 func LessThan(x, y Value) Value {
-	// TODO(quasilyte): support non-strict forms of comparison?
-	switch x := x.(type) {
-	case IntValue:
-		y, ok := y.(IntValue)
-		if ok {
-			return BoolValue(x < y)
-		}
-	case FloatValue:
-		y, ok := y.(FloatValue)
-		if ok {
-			return BoolValue(x < y)
-		}
-	case StringValue:
-		y, ok := y.(StringValue)
-		if ok {
-			return BoolValue(x < y)
-		}
+	res, ok := compare(x, y)
+	if !ok {
+		return UnknownValue{}
 	}
-	return UnknownValue{}
+	return BoolValue(res == -1)
+}
+
+// LessOrEqual performs "<=" comparison.
+func LessOrEqual(x, y Value) Value {
+	res, ok := compare(x, y)
+	if !ok {
+		return UnknownValue{}
+	}
+	return BoolValue(res == -1 || res == 0)
+}
+
+// Spaceship performs "<=>" comparison.
+func Spaceship(x, y Value) Value {
+	res, ok := compare(x, y)
+	if !ok {
+		return UnknownValue{}
+	}
+	if res == cmpUnordered {
+		return IntValue(1)
+	}
+	return IntValue(res)
 }
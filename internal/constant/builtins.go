@@ -0,0 +1,973 @@
+package constant
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxStringLen is a limit for strings that are produced by the
+// builtin functions evaluation, like str_repeat.
+const maxStringLen = 64 * 1024
+
+func init() {
+	builtins := map[string]Func{
+		`\strlen`:      builtinStrlen,
+		`\strtolower`:  builtinStrtolower,
+		`\strtoupper`:  builtinStrtoupper,
+		`\ucfirst`:     builtinUcfirst,
+		`\lcfirst`:     builtinLcfirst,
+		`\ucwords`:     builtinUcwords,
+		`\strrev`:      builtinStrrev,
+		`\substr`:      builtinSubstr,
+		`\str_repeat`:  builtinStrRepeat,
+		`\str_pad`:     builtinStrPad,
+		`\trim`:        builtinTrim,
+		`\ltrim`:       builtinLtrim,
+		`\rtrim`:       builtinRtrim,
+		`\chop`:        builtinRtrim,
+		`\sprintf`:     builtinSprintf,
+		`\implode`:     builtinImplode,
+		`\join`:        builtinImplode,
+		`\ord`:         builtinOrd,
+		`\chr`:         builtinChr,
+		`\strpos`:      builtinStrpos,
+		`\stripos`:     builtinStripos,
+		`\strrpos`:     builtinStrrpos,
+		`\strcmp`:      builtinStrcmp,
+		`\strcasecmp`:  builtinStrcasecmp,
+		`\str_replace`: builtinStrReplace,
+
+		`\str_contains`:    builtinStrContains,
+		`\str_starts_with`: builtinStrStartsWith,
+		`\str_ends_with`:   builtinStrEndsWith,
+
+		`\abs`:    builtinAbs,
+		`\min`:    builtinMin,
+		`\max`:    builtinMax,
+		`\intdiv`: builtinIntdiv,
+		`\floor`:  builtinFloor,
+		`\ceil`:   builtinCeil,
+		`\round`:  builtinRound,
+		`\sqrt`:   builtinSqrt,
+		`\pi`:     builtinPi,
+		`\dechex`: builtinDechex,
+		`\decbin`: builtinDecbin,
+		`\decoct`: builtinDecoct,
+		`\hexdec`: builtinHexdec,
+		`\bindec`: builtinBindec,
+		`\octdec`: builtinOctdec,
+
+		`\intval`:    builtinIntval,
+		`\floatval`:  builtinFloatval,
+		`\doubleval`: builtinFloatval,
+		`\strval`:    builtinStrval,
+		`\boolval`:   builtinBoolval,
+
+		`\is_null`:    builtinIsNull,
+		`\is_int`:     builtinIsInt,
+		`\is_integer`: builtinIsInt,
+		`\is_long`:    builtinIsInt,
+		`\is_float`:   builtinIsFloat,
+		`\is_double`:  builtinIsFloat,
+		`\is_string`:  builtinIsString,
+		`\is_bool`:    builtinIsBool,
+		`\is_array`:   builtinIsArray,
+		`\is_scalar`:  builtinIsScalar,
+		`\is_numeric`: builtinIsNumeric,
+
+		`\count`:            builtinCount,
+		`\sizeof`:           builtinCount,
+		`\in_array`:         builtinInArray,
+		`\array_key_exists`: builtinArrayKeyExists,
+		`\key_exists`:       builtinArrayKeyExists,
+		`\array_keys`:       builtinArrayKeys,
+		`\array_values`:     builtinArrayValues,
+		`\array_merge`:      builtinArrayMerge,
+	}
+	for name, fn := range builtins {
+		RegisterFunc(name, fn)
+	}
+}
+
+// stringArg converts function argument to string.
+func stringArg(x Value) (string, bool) {
+	if _, ok := x.(*ArrayValue); ok {
+		return "", false
+	}
+	s, ok := ToString(x)
+	return string(s), ok
+}
+
+// intArg converts function argument to int.
+//
+// Like PHP, it accepts only those floats and numeric strings
+// that represent integral values.
+func intArg(x Value) (IntValue, bool) {
+	switch x := x.(type) {
+	case IntValue:
+		return x, true
+	case BoolValue:
+		return ToInt(x)
+	case NullValue:
+		return 0, true
+	case FloatValue:
+		if float64(x) != math.Trunc(float64(x)) {
+			return 0, false
+		}
+		return floatToInt(float64(x))
+	case StringValue:
+		v, ok := parseNumericString(string(x))
+		if !ok {
+			return 0, false
+		}
+		return intArg(v)
+	}
+	return 0, false
+}
+
+// numberArg converts function argument to int or float.
+func numberArg(x Value) (Value, bool) {
+	switch x := x.(type) {
+	case IntValue, FloatValue:
+		return x, true
+	case BoolValue, NullValue:
+		v, ok := ToInt(x)
+		return v, ok
+	case StringValue:
+		return parseNumericString(string(x))
+	}
+	return nil, false
+}
+
+func builtinStrlen(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return IntValue(len(s))
+}
+
+// mapBytes applies f to every s byte.
+func mapBytes(s string, f func(ch byte) byte) string {
+	res := []byte(s)
+	for i, ch := range res {
+		res[i] = f(ch)
+	}
+	return string(res)
+}
+
+func toLowerByte(ch byte) byte {
+	if ch >= 'A' && ch <= 'Z' {
+		return ch + ('a' - 'A')
+	}
+	return ch
+}
+
+func toUpperByte(ch byte) byte {
+	if ch >= 'a' && ch <= 'z' {
+		return ch - ('a' - 'A')
+	}
+	return ch
+}
+
+// stringFunc1 is a helper for string(string) functions.
+func stringFunc1(args []Value, f func(s string) string) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return StringValue(f(s))
+}
+
+func builtinStrtolower(args []Value) Value {
+	return stringFunc1(args, func(s string) string {
+		return mapBytes(s, toLowerByte)
+	})
+}
+
+func builtinStrtoupper(args []Value) Value {
+	return stringFunc1(args, func(s string) string {
+		return mapBytes(s, toUpperByte)
+	})
+}
+
+func builtinUcfirst(args []Value) Value {
+	return stringFunc1(args, func(s string) string {
+		if s == "" {
+			return s
+		}
+		return string(toUpperByte(s[0])) + s[1:]
+	})
+}
+
+func builtinLcfirst(args []Value) Value {
+	return stringFunc1(args, func(s string) string {
+		if s == "" {
+			return s
+		}
+		return string(toLowerByte(s[0])) + s[1:]
+	})
+}
+
+func builtinUcwords(args []Value) Value {
+	delims := " \t\r\n\f\v"
+	if len(args) == 2 {
+		s, ok := stringArg(args[1])
+		if !ok {
+			return UnknownValue{}
+		}
+		delims = s
+		args = args[:1]
+	}
+	return stringFunc1(args, func(s string) string {
+		res := []byte(s)
+		for i := range res {
+			if i == 0 || strings.IndexByte(delims, res[i-1]) != -1 {
+				res[i] = toUpperByte(res[i])
+			}
+		}
+		return string(res)
+	})
+}
+
+func builtinStrrev(args []Value) Value {
+	return stringFunc1(args, func(s string) string {
+		res := make([]byte, len(s))
+		for i := 0; i < len(s); i++ {
+			res[len(s)-i-1] = s[i]
+		}
+		return string(res)
+	})
+}
+
+func builtinSubstr(args []Value) Value {
+	if len(args) != 2 && len(args) != 3 {
+		return UnknownValue{}
+	}
+	s, ok1 := stringArg(args[0])
+	start, ok2 := intArg(args[1])
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	n := IntValue(len(s))
+	length := n
+	if len(args) == 3 {
+		if _, ok := args[2].(NullValue); !ok {
+			v, ok := intArg(args[2])
+			if !ok {
+				return UnknownValue{}
+			}
+			length = v
+		}
+	}
+
+	if start > n {
+		if TargetVersion >= PHP8 {
+			return StringValue("")
+		}
+		return BoolValue(false)
+	}
+	if start < 0 {
+		start += n
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := n
+	switch {
+	case length < 0:
+		end = n + length
+		if end < start {
+			if TargetVersion >= PHP8 {
+				return StringValue("")
+			}
+			return BoolValue(false)
+		}
+	case length < n-start:
+		// Compared before the addition, so huge lengths don't overflow.
+		end = start + length
+	}
+	return StringValue(s[start:end])
+}
+
+func builtinStrRepeat(args []Value) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	s, ok1 := stringArg(args[0])
+	n, ok2 := intArg(args[1])
+	if !ok1 || !ok2 || n < 0 {
+		return UnknownValue{}
+	}
+	if len(s) != 0 && n > maxStringLen/IntValue(len(s)) {
+		return UnknownValue{}
+	}
+	return StringValue(strings.Repeat(s, int(n)))
+}
+
+// str_pad type argument values.
+const (
+	strPadLeft  = 0
+	strPadRight = 1
+	strPadBoth  = 2
+)
+
+func builtinStrPad(args []Value) Value {
+	if len(args) < 2 || len(args) > 4 {
+		return UnknownValue{}
+	}
+	s, ok1 := stringArg(args[0])
+	length, ok2 := intArg(args[1])
+	if !ok1 || !ok2 || length > maxStringLen {
+		return UnknownValue{}
+	}
+	pad := " "
+	if len(args) >= 3 {
+		v, ok := stringArg(args[2])
+		if !ok || v == "" {
+			return UnknownValue{}
+		}
+		pad = v
+	}
+	padType := IntValue(strPadRight)
+	if len(args) == 4 {
+		v, ok := intArg(args[3])
+		if !ok {
+			return UnknownValue{}
+		}
+		padType = v
+	}
+
+	if length <= IntValue(len(s)) {
+		return StringValue(s)
+	}
+	total := int(length) - len(s)
+	makePad := func(n int) string {
+		return strings.Repeat(pad, n/len(pad)+1)[:n]
+	}
+	switch padType {
+	case strPadLeft:
+		return StringValue(makePad(total) + s)
+	case strPadRight:
+		return StringValue(s + makePad(total))
+	case strPadBoth:
+		left := total / 2
+		return StringValue(makePad(left) + s + makePad(total-left))
+	}
+	return UnknownValue{}
+}
+
+// trimCharset expands trim() character list that can contain "a..z" ranges.
+func trimCharset(chars string) (string, bool) {
+	if !strings.Contains(chars, "..") {
+		return chars, true
+	}
+	var out strings.Builder
+	for i := 0; i < len(chars); i++ {
+		if i+3 < len(chars) && chars[i+1] == '.' && chars[i+2] == '.' {
+			from, to := chars[i], chars[i+3]
+			if from > to {
+				return "", false
+			}
+			for ch := int(from); ch <= int(to); ch++ {
+				out.WriteByte(byte(ch))
+			}
+			i += 3
+			continue
+		}
+		out.WriteByte(chars[i])
+	}
+	return out.String(), true
+}
+
+func trimFunc(args []Value, left, right bool) Value {
+	if len(args) != 1 && len(args) != 2 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	chars := " \t\n\r\x00\x0B"
+	if len(args) == 2 {
+		v, ok := stringArg(args[1])
+		if !ok {
+			return UnknownValue{}
+		}
+		chars, ok = trimCharset(v)
+		if !ok {
+			return UnknownValue{}
+		}
+	}
+	if left {
+		s = strings.TrimLeft(s, chars)
+	}
+	if right {
+		s = strings.TrimRight(s, chars)
+	}
+	return StringValue(s)
+}
+
+func builtinTrim(args []Value) Value  { return trimFunc(args, true, true) }
+func builtinLtrim(args []Value) Value { return trimFunc(args, true, false) }
+func builtinRtrim(args []Value) Value { return trimFunc(args, false, true) }
+
+func builtinSprintf(args []Value) Value {
+	if len(args) == 0 {
+		return UnknownValue{}
+	}
+	format, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	s, ok := sprintf(format, args[1:])
+	if !ok {
+		return UnknownValue{}
+	}
+	return StringValue(s)
+}
+
+func builtinImplode(args []Value) Value {
+	var sep Value = StringValue("")
+	var arr *ArrayValue
+	switch len(args) {
+	case 1:
+		v, ok := args[0].(*ArrayValue)
+		if !ok {
+			return UnknownValue{}
+		}
+		arr = v
+	case 2:
+		// Legacy implode($pieces, $glue) form is also supported.
+		if v, ok := args[1].(*ArrayValue); ok {
+			sep, arr = args[0], v
+		} else if v, ok := args[0].(*ArrayValue); ok {
+			sep, arr = args[1], v
+		} else {
+			return UnknownValue{}
+		}
+	default:
+		return UnknownValue{}
+	}
+	glue, ok := stringArg(sep)
+	if !ok {
+		return UnknownValue{}
+	}
+	parts := make([]string, 0, arr.Len())
+	for _, v := range arr.values {
+		s, ok := stringArg(v)
+		if !ok {
+			return UnknownValue{}
+		}
+		parts = append(parts, s)
+	}
+	return StringValue(strings.Join(parts, glue))
+}
+
+func builtinOrd(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	if s == "" {
+		return IntValue(0)
+	}
+	return IntValue(s[0])
+}
+
+func builtinChr(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	n, ok := intArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	n %= 256
+	if n < 0 {
+		n += 256
+	}
+	return StringValue([]byte{byte(n)})
+}
+
+// strposFunc implements strpos-like functions.
+func strposFunc(args []Value, index func(haystack, needle string) int) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	haystack, ok1 := stringArg(args[0])
+	needle, ok2 := stringArg(args[1])
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	if needle == "" && TargetVersion < PHP8 {
+		return UnknownValue{}
+	}
+	if i := index(haystack, needle); i != -1 {
+		return IntValue(i)
+	}
+	return BoolValue(false)
+}
+
+func builtinStrpos(args []Value) Value {
+	return strposFunc(args, strings.Index)
+}
+
+func builtinStripos(args []Value) Value {
+	return strposFunc(args, func(haystack, needle string) int {
+		return strings.Index(mapBytes(haystack, toLowerByte), mapBytes(needle, toLowerByte))
+	})
+}
+
+func builtinStrrpos(args []Value) Value {
+	return strposFunc(args, func(haystack, needle string) int {
+		if needle == "" {
+			return len(haystack)
+		}
+		return strings.LastIndex(haystack, needle)
+	})
+}
+
+// strcmpFunc implements strcmp-like functions.
+//
+// PHP 8 always returns -1, 0 or 1, while PHP 7 result
+// magnitude is unspecified, so only equality is folded.
+func strcmpFunc(args []Value, normalize func(s string) string) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	s1, ok1 := stringArg(args[0])
+	s2, ok2 := stringArg(args[1])
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	res := strings.Compare(normalize(s1), normalize(s2))
+	if res != 0 && TargetVersion < PHP8 {
+		return UnknownValue{}
+	}
+	return IntValue(res)
+}
+
+func builtinStrcmp(args []Value) Value {
+	return strcmpFunc(args, func(s string) string { return s })
+}
+
+func builtinStrcasecmp(args []Value) Value {
+	return strcmpFunc(args, func(s string) string { return mapBytes(s, toLowerByte) })
+}
+
+func builtinStrReplace(args []Value) Value {
+	if len(args) != 3 {
+		return UnknownValue{}
+	}
+	search, ok1 := stringArg(args[0])
+	replace, ok2 := stringArg(args[1])
+	subject, ok3 := stringArg(args[2])
+	if !ok1 || !ok2 || !ok3 {
+		return UnknownValue{}
+	}
+	if search == "" {
+		return StringValue(subject)
+	}
+	res := strings.Replace(subject, search, replace, -1)
+	if len(res) > maxStringLen {
+		return UnknownValue{}
+	}
+	return StringValue(res)
+}
+
+// stringPredicate is a helper for bool(string, string) functions.
+func stringPredicate(args []Value, f func(s1, s2 string) bool) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	s1, ok1 := stringArg(args[0])
+	s2, ok2 := stringArg(args[1])
+	if !ok1 || !ok2 {
+		return UnknownValue{}
+	}
+	return BoolValue(f(s1, s2))
+}
+
+func builtinStrContains(args []Value) Value {
+	return stringPredicate(args, strings.Contains)
+}
+
+func builtinStrStartsWith(args []Value) Value {
+	return stringPredicate(args, strings.HasPrefix)
+}
+
+func builtinStrEndsWith(args []Value) Value {
+	return stringPredicate(args, strings.HasSuffix)
+}
+
+func builtinAbs(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	v, ok := numberArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	if toFloat64(v) < 0 || math.Signbit(toFloat64(v)) {
+		return Neg(v)
+	}
+	return v
+}
+
+// minmax implements min and max functions.
+// The winner is replaced only if better(compare(arg, winner)) is true.
+func minmax(args []Value, better func(cmp int) bool) Value {
+	if len(args) == 1 {
+		arr, ok := args[0].(*ArrayValue)
+		if !ok || arr.Len() == 0 {
+			return UnknownValue{}
+		}
+		args = arr.values
+	}
+	if len(args) == 0 {
+		return UnknownValue{}
+	}
+	res := args[0]
+	for _, arg := range args[1:] {
+		cmp, ok := compare(arg, res)
+		if !ok {
+			return UnknownValue{}
+		}
+		if better(cmp) {
+			res = arg
+		}
+	}
+	return res
+}
+
+func builtinMin(args []Value) Value {
+	return minmax(args, func(cmp int) bool { return cmp == -1 })
+}
+
+func builtinMax(args []Value) Value {
+	return minmax(args, func(cmp int) bool { return cmp == 1 })
+}
+
+func builtinIntdiv(args []Value) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	a, ok1 := intArg(args[0])
+	b, ok2 := intArg(args[1])
+	if !ok1 || !ok2 || b == 0 || (a == math.MinInt64 && b == -1) {
+		// DivisionByZeroError and ArithmeticError.
+		return UnknownValue{}
+	}
+	return a / b
+}
+
+// floatFunc1 is a helper for float(number) functions.
+func floatFunc1(args []Value, f func(x float64) float64) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	v, ok := numberArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return FloatValue(f(toFloat64(v)))
+}
+
+func builtinFloor(args []Value) Value { return floatFunc1(args, math.Floor) }
+func builtinCeil(args []Value) Value  { return floatFunc1(args, math.Ceil) }
+func builtinSqrt(args []Value) Value  { return floatFunc1(args, math.Sqrt) }
+
+func builtinRound(args []Value) Value {
+	// Only the default precision is supported:
+	// rounding to the decimal digits has a few surprising PHP-specific rules.
+	return floatFunc1(args, math.Round)
+}
+
+func builtinPi(args []Value) Value {
+	if len(args) != 0 {
+		return UnknownValue{}
+	}
+	return FloatValue(math.Pi)
+}
+
+// formatUintFunc is a helper for dechex-like functions.
+func formatUintFunc(args []Value, base int) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	n, ok := intArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return StringValue(strconv.FormatUint(uint64(n), base))
+}
+
+func builtinDechex(args []Value) Value { return formatUintFunc(args, 16) }
+func builtinDecbin(args []Value) Value { return formatUintFunc(args, 2) }
+func builtinDecoct(args []Value) Value { return formatUintFunc(args, 8) }
+
+// parseUintFunc is a helper for hexdec-like functions.
+// Like PHP, it ignores all invalid digits.
+// Results that don't fit into int are returned as floats.
+func parseUintFunc(args []Value, base int) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	var n IntValue
+	var f float64
+	isFloat := false
+	for i := 0; i < len(s); i++ {
+		d, err := strconv.ParseUint(s[i:i+1], base, 8)
+		if err != nil {
+			continue
+		}
+		if !isFloat {
+			if n > (math.MaxInt64-IntValue(d))/IntValue(base) {
+				isFloat = true
+				f = float64(n)
+			} else {
+				n = n*IntValue(base) + IntValue(d)
+				continue
+			}
+		}
+		f = f*float64(base) + float64(d)
+	}
+	if isFloat {
+		return FloatValue(f)
+	}
+	return n
+}
+
+func builtinHexdec(args []Value) Value { return parseUintFunc(args, 16) }
+func builtinBindec(args []Value) Value { return parseUintFunc(args, 2) }
+func builtinOctdec(args []Value) Value { return parseUintFunc(args, 8) }
+
+func builtinIntval(args []Value) Value {
+	if len(args) == 2 {
+		base, ok := intArg(args[1])
+		if !ok || base != 10 {
+			return UnknownValue{}
+		}
+		args = args[:1]
+	}
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	v, ok := ToInt(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return v
+}
+
+func builtinFloatval(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	v, ok := ToFloat(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return v
+}
+
+func builtinStrval(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	s, ok := stringArg(args[0])
+	if !ok {
+		return UnknownValue{}
+	}
+	return StringValue(s)
+}
+
+func builtinBoolval(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	return Not(Not(args[0]))
+}
+
+// typePredicate is a helper for is_* functions.
+func typePredicate(args []Value, f func(x Value) bool) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	return BoolValue(f(args[0]))
+}
+
+func builtinIsNull(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(NullValue)
+		return ok
+	})
+}
+
+func builtinIsInt(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(IntValue)
+		return ok
+	})
+}
+
+func builtinIsFloat(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(FloatValue)
+		return ok
+	})
+}
+
+func builtinIsString(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(StringValue)
+		return ok
+	})
+}
+
+func builtinIsBool(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(BoolValue)
+		return ok
+	})
+}
+
+func builtinIsArray(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		_, ok := x.(*ArrayValue)
+		return ok
+	})
+}
+
+func builtinIsScalar(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		switch x.(type) {
+		case IntValue, FloatValue, StringValue, BoolValue:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+func builtinIsNumeric(args []Value) Value {
+	return typePredicate(args, func(x Value) bool {
+		switch x := x.(type) {
+		case IntValue, FloatValue:
+			return true
+		case StringValue:
+			_, ok := parseNumericString(string(x))
+			return ok
+		default:
+			return false
+		}
+	})
+}
+
+func builtinCount(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	arr, ok := args[0].(*ArrayValue)
+	if !ok {
+		return UnknownValue{}
+	}
+	return IntValue(arr.Len())
+}
+
+func builtinInArray(args []Value) Value {
+	if len(args) != 2 && len(args) != 3 {
+		return UnknownValue{}
+	}
+	arr, ok := args[1].(*ArrayValue)
+	if !ok {
+		return UnknownValue{}
+	}
+	strict := BoolValue(false)
+	if len(args) == 3 {
+		strict, ok = ToBool(args[2])
+		if !ok {
+			return UnknownValue{}
+		}
+	}
+	return arr.Contains(args[0], bool(strict))
+}
+
+func builtinArrayKeyExists(args []Value) Value {
+	if len(args) != 2 {
+		return UnknownValue{}
+	}
+	arr, ok := args[1].(*ArrayValue)
+	if !ok {
+		return UnknownValue{}
+	}
+	if _, ok := NormalizeKey(args[0]); !ok {
+		return UnknownValue{}
+	}
+	_, ok = arr.Get(args[0])
+	return BoolValue(ok)
+}
+
+func builtinArrayKeys(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	arr, ok := args[0].(*ArrayValue)
+	if !ok {
+		return UnknownValue{}
+	}
+	res := NewArray()
+	for _, k := range arr.keys {
+		res.Append(k)
+	}
+	return res
+}
+
+func builtinArrayValues(args []Value) Value {
+	if len(args) != 1 {
+		return UnknownValue{}
+	}
+	arr, ok := args[0].(*ArrayValue)
+	if !ok {
+		return UnknownValue{}
+	}
+	res := NewArray()
+	for _, v := range arr.values {
+		res.Append(v)
+	}
+	return res
+}
+
+func builtinArrayMerge(args []Value) Value {
+	res := NewArray()
+	for _, arg := range args {
+		arr, ok := arg.(*ArrayValue)
+		if !ok {
+			return UnknownValue{}
+		}
+		arr.Iterate(func(k, v Value) {
+			// Int keys are renumbered, string keys are overwritten.
+			if _, ok := k.(IntValue); ok {
+				res.Append(v)
+			} else {
+				res.Set(k, v)
+			}
+		})
+	}
+	return res
+}
@@ -0,0 +1,161 @@
+package constant
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cmpUnordered is a compare result for values that are neither
+// less, equal nor greater than each other (like NAN operands).
+const cmpUnordered = 2
+
+// compare implements PHP loose comparison rules.
+//
+// Result is -1, 0 or 1 if x is less, equal or greater than y.
+// For unordered operands cmpUnordered is returned.
+// Second bool result tells whether the comparison was successful.
+func compare(x, y Value) (int, bool) {
+	if !x.isValid() || !y.isValid() {
+		return 0, false
+	}
+
+	// null is compared with strings as an empty string.
+	_, xIsNull := x.(NullValue)
+	_, yIsNull := y.(NullValue)
+	if s, ok := y.(StringValue); ok && xIsNull {
+		return strings.Compare("", string(s)), true
+	}
+	if s, ok := x.(StringValue); ok && yIsNull {
+		return strings.Compare(string(s), ""), true
+	}
+
+	// If either operand is bool or null, both of them are compared as bools.
+	_, xIsBool := x.(BoolValue)
+	_, yIsBool := y.(BoolValue)
+	if xIsBool || yIsBool || xIsNull || yIsNull {
+		b1, ok1 := ToBool(x)
+		b2, ok2 := ToBool(y)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return compareBools(b1, b2), true
+	}
+
+	// Arrays are always greater than non-array values.
+	xArray, xIsArray := x.(*ArrayValue)
+	yArray, yIsArray := y.(*ArrayValue)
+	switch {
+	case xIsArray && yIsArray:
+		return compareArrays(xArray, yArray)
+	case xIsArray:
+		return 1, true
+	case yIsArray:
+		return -1, true
+	}
+
+	switch x := x.(type) {
+	case IntValue, FloatValue:
+		switch y := y.(type) {
+		case IntValue, FloatValue:
+			return compareNumbers(x, y), true
+		case StringValue:
+			return compareNumberString(x, y)
+		}
+	case StringValue:
+		switch y := y.(type) {
+		case IntValue, FloatValue:
+			res, ok := compareNumberString(y, x)
+			return invertCmp(res), ok
+		case StringValue:
+			v1, ok1 := parseNumericString(string(x))
+			v2, ok2 := parseNumericString(string(y))
+			if ok1 && ok2 {
+				return compareNumbers(v1, v2), true
+			}
+			return strings.Compare(string(x), string(y)), true
+		}
+	}
+
+	return 0, false
+}
+
+func invertCmp(res int) int {
+	if res == cmpUnordered {
+		return res
+	}
+	return -res
+}
+
+func compareBools(x, y BoolValue) int {
+	switch {
+	case x == y:
+		return 0
+	case !bool(x):
+		return -1
+	default:
+		return 1
+	}
+}
+
+// compareNumberString compares int or float x with string y.
+func compareNumberString(x Value, y StringValue) (int, bool) {
+	if TargetVersion >= PHP8 {
+		// PHP 8 compares numbers with non-numeric strings as strings.
+		if v, ok := parseNumericString(string(y)); ok {
+			return compareNumbers(x, v), true
+		}
+		return strings.Compare(numberToString(x), string(y)), true
+	}
+	// PHP 7 converts string to number.
+	return compareNumbers(x, stringToNumber(string(y))), true
+}
+
+// compareNumbers compares two int or float values.
+func compareNumbers(x, y Value) int {
+	if x, ok := x.(IntValue); ok {
+		if y, ok := y.(IntValue); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	f1 := toFloat64(x)
+	f2 := toFloat64(y)
+	switch {
+	case f1 < f2:
+		return -1
+	case f1 > f2:
+		return 1
+	case f1 == f2:
+		return 0
+	default:
+		return cmpUnordered
+	}
+}
+
+func numberToString(x Value) string {
+	switch x := x.(type) {
+	case IntValue:
+		return strconv.FormatInt(int64(x), 10)
+	case FloatValue:
+		return formatFloat(float64(x))
+	default:
+		return ""
+	}
+}
+
+func toFloat64(x Value) float64 {
+	switch x := x.(type) {
+	case IntValue:
+		return float64(x)
+	case FloatValue:
+		return float64(x)
+	default:
+		return 0
+	}
+}
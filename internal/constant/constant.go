@@ -1,6 +1,7 @@
 package constant
 
 import (
+	"math"
 	"strconv"
 )
 
@@ -29,6 +30,9 @@ type (
 
 	// BoolValue is such an x value that is_bool($x) returns true.
 	BoolValue bool
+
+	// NullValue is such an x value that is_null($x) returns true.
+	NullValue struct{}
 )
 
 // ToBool converts x constant to boolean constants following PHP conversion rules.
@@ -43,6 +47,10 @@ func ToBool(x Value) (BoolValue, bool) {
 		return BoolValue(x != 0), true
 	case StringValue:
 		return BoolValue(x != "" && x != "0"), true
+	case NullValue:
+		return false, true
+	case *ArrayValue:
+		return BoolValue(x.Len() != 0), true
 	}
 	return false, false
 }
@@ -59,7 +67,47 @@ func ToInt(x Value) (IntValue, bool) {
 	case IntValue:
 		return x, true
 	case FloatValue:
-		return IntValue(x), true
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return 0, true
+		}
+		// Out of range values conversion result is platform-dependent.
+		return floatToInt(float64(x))
+	case StringValue:
+		// Only the leading numeric part is used, "12abc" is 12.
+		// Strings without such part are converted to 0.
+		v, _ := scanNumber(string(x))
+		if v == nil {
+			return 0, true
+		}
+		return ToInt(v)
+	case NullValue:
+		return 0, true
+	case *ArrayValue:
+		if x.Len() != 0 {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// ToFloat converts x constant to float constants following PHP conversion rules.
+// Second bool result tells whether that conversion was successful.
+func ToFloat(x Value) (FloatValue, bool) {
+	switch x := x.(type) {
+	case FloatValue:
+		return x, true
+	case IntValue:
+		return FloatValue(x), true
+	case StringValue:
+		v, _ := scanNumber(string(x))
+		if v == nil {
+			return 0, true
+		}
+		return FloatValue(toFloat64(v)), true
+	case BoolValue, NullValue, *ArrayValue:
+		v, ok := ToInt(x)
+		return FloatValue(v), ok
 	}
 	return 0, false
 }
@@ -75,8 +123,12 @@ func ToString(x Value) (StringValue, bool) {
 		return "", true
 	case IntValue:
 		return StringValue(strconv.FormatInt(int64(x), 10)), true
+	case FloatValue:
+		return StringValue(formatFloat(float64(x))), true
 	case StringValue:
 		return x, true
+	case NullValue:
+		return "", true
 	}
 	return "", false
 }
@@ -86,3 +138,4 @@ func (c IntValue) isValid() bool     { return true }
 func (c FloatValue) isValid() bool   { return true }
 func (c StringValue) isValid() bool  { return true }
 func (c BoolValue) isValid() bool    { return true }
+func (c NullValue) isValid() bool    { return true }
@@ -0,0 +1,279 @@
+package constant
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func withVersion(v Version, f func()) {
+	prev := TargetVersion
+	TargetVersion = v
+	defer func() { TargetVersion = prev }()
+	f()
+}
+
+func TestLooseCompare(t *testing.T) {
+	tests := []struct {
+		x, y Value
+		php7 int
+		php8 int
+	}{
+		{IntValue(1), IntValue(2), -1, -1},
+		{IntValue(1), FloatValue(1), 0, 0},
+		{FloatValue(1.5), IntValue(1), 1, 1},
+
+		{StringValue("1"), IntValue(1), 0, 0},
+		{StringValue("1.0"), IntValue(1), 0, 0},
+		{StringValue(" 1"), IntValue(1), 0, 0},
+		{StringValue("1 "), IntValue(1), 0, 0},
+		{StringValue("abc"), IntValue(0), 0, 1},
+		{StringValue("1abc"), IntValue(1), 0, 1},
+		{StringValue(""), IntValue(0), 0, -1},
+		{IntValue(10), StringValue("9a"), 1, -1},
+		{FloatValue(1.5), StringValue("1.5"), 0, 0},
+
+		{StringValue("abc"), StringValue("abd"), -1, -1},
+		{StringValue("10"), StringValue("9"), 1, 1},
+		{StringValue("1e3"), StringValue("1000"), 0, 0},
+		{StringValue("abc"), StringValue("ab"), 1, 1},
+		{StringValue("1 "), StringValue("1"), 1, 0},
+
+		{BoolValue(true), StringValue("abc"), 0, 0},
+		{BoolValue(false), StringValue("0"), 0, 0},
+		{BoolValue(false), IntValue(1), -1, -1},
+		{IntValue(-1), BoolValue(true), 0, 0},
+	}
+
+	for _, test := range tests {
+		for _, v := range []Version{PHP7, PHP8} {
+			want := test.php7
+			if v == PHP8 {
+				want = test.php8
+			}
+			withVersion(v, func() {
+				have := Spaceship(test.x, test.y)
+				if have != IntValue(want) {
+					t.Errorf("PHP%d: %#v <=> %#v: have %v, want %d",
+						v, test.x, test.y, have, want)
+				}
+				eq := Equal(test.x, test.y)
+				if eq != BoolValue(want == 0) {
+					t.Errorf("PHP%d: %#v == %#v: have %v, want %v",
+						v, test.x, test.y, eq, want == 0)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{-1.5, "-1.5"},
+		{0.1 + 0.2, "0.3"},
+		{100, "100"},
+		{1e14, "1.0E+14"},
+		{1.5e15, "1.5E+15"},
+		{0.0001, "0.0001"},
+		{0.00001, "1.0E-5"},
+		{123456.789, "123456.789"},
+		{1.0 / 3, "0.33333333333333"},
+	}
+
+	for _, test := range tests {
+		have := formatFloat(test.f)
+		if have != test.want {
+			t.Errorf("formatFloat(%v): have %q, want %q", test.f, have, test.want)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []Value
+		want   string
+	}{
+		{"%s-%s", []Value{StringValue("a"), IntValue(1)}, "a-1"},
+		{"%5s|%-5s|", []Value{StringValue("ab"), StringValue("cd")}, "   ab|cd   |"},
+		{"%'.6d", []Value{IntValue(42)}, "....42"},
+		{"%05d", []Value{IntValue(-42)}, "-0042"},
+		{"%+d %+d", []Value{IntValue(5), IntValue(-5)}, "+5 -5"},
+		{"%.2f", []Value{FloatValue(1.005)}, "1.00"},
+		{"%f", []Value{StringValue("1.5")}, "1.500000"},
+		{"%e", []Value{FloatValue(12.345)}, "1.234500e+1"},
+		{"%x %X %o %b %c", []Value{IntValue(255), IntValue(255), IntValue(8), IntValue(5), IntValue(65)}, "ff FF 10 101 A"},
+		{"%u", []Value{IntValue(-1)}, "18446744073709551615"},
+		{"%.3s", []Value{StringValue("abcdef")}, "abc"},
+		{"%.2000000000f", []Value{IntValue(1)}, "1." + strings.Repeat("0", 53)},
+		{"%2$s %1$s %2$s", []Value{StringValue("a"), StringValue("b")}, "b a b"},
+		{"100%%", nil, "100%"},
+	}
+
+	for _, test := range tests {
+		have := Call(`\sprintf`, append([]Value{StringValue(test.format)}, test.args...))
+		if have != StringValue(test.want) {
+			t.Errorf("sprintf(%q, %v): have %#v, want %q", test.format, test.args, have, test.want)
+		}
+	}
+
+	for _, format := range []string{"%", "%d %d", "%0$s", "%y", "%60000d%1$60000d"} {
+		have := Call(`\sprintf`, []Value{StringValue(format), IntValue(1)})
+		if have != (UnknownValue{}) {
+			t.Errorf("sprintf(%q, 1): have %#v, want unknown", format, have)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []Value
+		want Value
+	}{
+		{`\substr`, []Value{StringValue("hello"), IntValue(1), IntValue(3)}, StringValue("ell")},
+		{`\substr`, []Value{StringValue("hello"), IntValue(-3)}, StringValue("llo")},
+		{`\substr`, []Value{StringValue("hello"), IntValue(1), IntValue(-1)}, StringValue("ell")},
+		{`\substr`, []Value{StringValue("abc"), IntValue(1), IntValue(math.MaxInt64)}, StringValue("bc")},
+		{`\substr`, []Value{StringValue("abc"), IntValue(math.MinInt64)}, StringValue("abc")},
+		{`\substr`, []Value{StringValue("abc"), IntValue(0), IntValue(math.MinInt64)}, BoolValue(false)},
+
+		{`\str_repeat`, []Value{StringValue("ab"), IntValue(3)}, StringValue("ababab")},
+		{`\str_repeat`, []Value{StringValue(""), IntValue(math.MaxInt64)}, StringValue("")},
+		{`\str_repeat`, []Value{StringValue("ab"), IntValue(math.MaxInt64)}, UnknownValue{}},
+		{`\str_repeat`, []Value{StringValue("ab"), IntValue(maxStringLen)}, UnknownValue{}},
+
+		{`\str_pad`, []Value{StringValue("ab"), IntValue(5), StringValue("xy"), IntValue(strPadBoth)}, StringValue("xabxy")},
+		{`\str_pad`, []Value{StringValue("ab"), IntValue(1)}, StringValue("ab")},
+		{`\str_pad`, []Value{StringValue("ab"), IntValue(math.MinInt64)}, StringValue("ab")},
+		{`\str_pad`, []Value{StringValue("ab"), IntValue(math.MaxInt64)}, UnknownValue{}},
+	}
+
+	withVersion(PHP7, func() {
+		for _, test := range tests {
+			have := Call(test.fn, test.args)
+			if have != test.want {
+				t.Errorf("%s%v: have %#v, want %#v", test.fn, test.args, have, test.want)
+			}
+		}
+	})
+}
+
+func TestRegisterFunc(t *testing.T) {
+	RegisterFunc(`\Foo\Twice`, func(args []Value) Value {
+		if len(args) != 1 {
+			return UnknownValue{}
+		}
+		return Mul(args[0], IntValue(2))
+	})
+	defer delete(funcs, `\foo\twice`)
+
+	if have := Call(`\foo\twice`, []Value{IntValue(5)}); have != IntValue(10) {
+		t.Errorf("twice(5): have %#v, want 10", have)
+	}
+	if have := Call(`\foo\twice`, []Value{UnknownValue{}}); have != (UnknownValue{}) {
+		t.Errorf("twice(unknown): have %#v, want unknown", have)
+	}
+	if have := Call(`\foo\undefined`, nil); have != (UnknownValue{}) {
+		t.Errorf("undefined(): have %#v, want unknown", have)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want Value
+	}{
+		{"0", IntValue(0)},
+		{"42", IntValue(42)},
+		{"1_000_000", IntValue(1000000)},
+		{"0x1F", IntValue(31)},
+		{"0XfF", IntValue(255)},
+		{"0x7FFF_FFFF", IntValue(0x7FFFFFFF)},
+		{"0b1010", IntValue(10)},
+		{"0B1_0", IntValue(2)},
+		{"0777", IntValue(511)},
+		{"00", IntValue(0)},
+		{"0_7", IntValue(7)},
+		{"0o17", IntValue(15)},
+		{"0O1_7", IntValue(15)},
+		{"9223372036854775807", IntValue(math.MaxInt64)},
+		{"9223372036854775808", FloatValue(9223372036854775808)},
+		{"0x8000000000000000", FloatValue(9223372036854775808)},
+		{"0xFFFFFFFFFFFFFFFF", FloatValue(18446744073709551615)},
+		{"0b" + strings.Repeat("1", 64), FloatValue(18446744073709551615)},
+		{"1.5", FloatValue(1.5)},
+		{".5", FloatValue(0.5)},
+		{"5.", FloatValue(5)},
+		{"1e3", FloatValue(1000)},
+		{"1E-3", FloatValue(0.001)},
+		{"1_0.2_5e1_0", FloatValue(10.25e10)},
+		{"1e400", FloatValue(math.Inf(1))},
+	}
+
+	for _, test := range tests {
+		have, ok := ParseNumber(test.lit)
+		if !ok {
+			t.Errorf("ParseNumber(%q): unexpected failure", test.lit)
+			continue
+		}
+		if have != test.want {
+			t.Errorf("ParseNumber(%q): have %#v, want %#v", test.lit, have, test.want)
+		}
+	}
+
+	for _, lit := range []string{"0x", "0b2", "089", "0o8", "1__0", "1_", "_1", "0x_1", "1._5", "1e", "1e+", "."} {
+		if have, ok := ParseNumber(lit); ok {
+			t.Errorf("ParseNumber(%q): expected an error, got %#v", lit, have)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		x Value
+		i Value
+		f Value
+		s Value
+		b BoolValue
+	}{
+		{IntValue(5), IntValue(5), FloatValue(5), StringValue("5"), true},
+		{FloatValue(1.5), IntValue(1), FloatValue(1.5), StringValue("1.5"), true},
+		{FloatValue(-1.9), IntValue(-1), FloatValue(-1.9), StringValue("-1.9"), true},
+		{FloatValue(math.Copysign(0, -1)), IntValue(0), FloatValue(math.Copysign(0, -1)), StringValue("-0"), false},
+		{FloatValue(1e20), UnknownValue{}, FloatValue(1e20), StringValue("1.0E+20"), true},
+		{FloatValue(math.NaN()), IntValue(0), nil, StringValue("NAN"), true},
+		{FloatValue(math.Inf(-1)), IntValue(0), FloatValue(math.Inf(-1)), StringValue("-INF"), true},
+		{StringValue("12abc"), IntValue(12), FloatValue(12), StringValue("12abc"), true},
+		{StringValue(" 1.5e3x"), IntValue(1500), FloatValue(1500), StringValue(" 1.5e3x"), true},
+		{StringValue("abc"), IntValue(0), FloatValue(0), StringValue("abc"), true},
+		{StringValue("0.0"), IntValue(0), FloatValue(0), StringValue("0.0"), true},
+		{StringValue("0"), IntValue(0), FloatValue(0), StringValue("0"), false},
+		{StringValue(""), IntValue(0), FloatValue(0), StringValue(""), false},
+		{StringValue("-0"), IntValue(0), FloatValue(0), StringValue("-0"), true},
+		{BoolValue(true), IntValue(1), FloatValue(1), StringValue("1"), true},
+		{NullValue{}, IntValue(0), FloatValue(0), StringValue(""), false},
+	}
+
+	for _, test := range tests {
+		if v, ok := ToInt(test.x); (ok && v != test.i) || (!ok && test.i != (UnknownValue{})) {
+			t.Errorf("ToInt(%#v): have %#v, want %#v", test.x, v, test.i)
+		}
+		if test.f != nil {
+			if v, ok := ToFloat(test.x); !ok || v != test.f || math.Signbit(float64(v)) != math.Signbit(toFloat64(test.f)) {
+				t.Errorf("ToFloat(%#v): have %#v, want %#v", test.x, v, test.f)
+			}
+		}
+		if v, ok := ToString(test.x); !ok || v != test.s {
+			t.Errorf("ToString(%#v): have %#v, want %#v", test.x, v, test.s)
+		}
+		if v, ok := ToBool(test.x); !ok || v != test.b {
+			t.Errorf("ToBool(%#v): have %#v, want %#v", test.x, v, test.b)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package constant
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Func evaluates a pure function call over the constant arguments.
+//
+// All args are known (valid) values.
+// If call result can't be computed, UnknownValue should be returned.
+type Func func(args []Value) Value
+
+var funcs = map[string]Func{}
+
+// RegisterFunc binds fn evaluator to the fully-qualified function name, like `\strlen`.
+//
+// Registered functions must be pure: given the same arguments,
+// they always return the same result and have no side effects.
+// It's possible to replace already registered function.
+func RegisterFunc(name string, fn Func) {
+	if !strings.HasPrefix(name, `\`) {
+		panic(fmt.Sprintf("RegisterFunc(%q): name is not fully-qualified", name))
+	}
+	funcs[strings.ToLower(name)] = fn
+}
+
+// LookupFunc returns an evaluator for the fully-qualified function name.
+// Function names are case-insensitive.
+func LookupFunc(name string) (Func, bool) {
+	fn, ok := funcs[strings.ToLower(name)]
+	return fn, ok
+}
+
+// Call evaluates name function call with args.
+// If function is not registered or any of the args is unknown,
+// UnknownValue is returned.
+func Call(name string, args []Value) Value {
+	fn, ok := LookupFunc(name)
+	if !ok {
+		return UnknownValue{}
+	}
+	for _, arg := range args {
+		if !arg.isValid() {
+			return UnknownValue{}
+		}
+	}
+	return fn(args)
+}
@@ -0,0 +1,148 @@
+package constant
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseNumber parses PHP integer or float literal.
+//
+// All PHP integer forms are recognized: decimal, hex (0x1F),
+// binary (0b101), legacy octal (0777) and explicit octal (0o777).
+// Digits can be separated by a single "_", like in 1_000_000.
+// Integer literals that overflow int are parsed as floats.
+func ParseNumber(lit string) (Value, bool) {
+	if len(lit) > 1 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			return parseIntLiteral(lit[2:], 16)
+		case 'b', 'B':
+			return parseIntLiteral(lit[2:], 2)
+		case 'o', 'O':
+			return parseIntLiteral(lit[2:], 8)
+		}
+	}
+	if strings.ContainsAny(lit, ".eE") {
+		return parseFloatLiteral(lit)
+	}
+	if len(lit) > 1 && lit[0] == '0' {
+		return parseIntLiteral(lit, 8)
+	}
+	return parseIntLiteral(lit, 10)
+}
+
+// removeSeparators validates "_" digit separators and returns s without them.
+// isDigit reports whether a char is a valid digit on the "_" sides.
+func removeSeparators(s string, isDigit func(ch byte) bool) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1]) {
+			return "", false
+		}
+	}
+	return strings.Replace(s, "_", "", -1), true
+}
+
+func parseIntLiteral(digits string, base int) (Value, bool) {
+	isBaseDigit := func(ch byte) bool {
+		d, ok := digitValue(ch)
+		return ok && d < base
+	}
+	digits, ok := removeSeparators(digits, isBaseDigit)
+	if !ok || digits == "" {
+		return nil, false
+	}
+
+	var n uint64
+	var f float64
+	overflow := false
+	for i := 0; i < len(digits); i++ {
+		if !isBaseDigit(digits[i]) {
+			return nil, false
+		}
+		d, _ := digitValue(digits[i])
+		if !overflow && n > (math.MaxInt64-uint64(d))/uint64(base) {
+			overflow = true
+			f = float64(n)
+		}
+		if overflow {
+			f = f*float64(base) + float64(d)
+		} else {
+			n = n*uint64(base) + uint64(d)
+		}
+	}
+
+	if !overflow {
+		return IntValue(n), true
+	}
+	if base == 10 {
+		// Decimal literals are rounded correctly.
+		v, _ := strconv.ParseFloat(digits, 64)
+		return FloatValue(v), true
+	}
+	return FloatValue(f), true
+}
+
+func parseFloatLiteral(lit string) (Value, bool) {
+	s, ok := removeSeparators(lit, isDigit)
+	if !ok {
+		return nil, false
+	}
+
+	// Validate the grammar: digits? ("." digits?)? ([eE] [+-]? digits)?
+	// at least one mantissa digit is required.
+	i := 0
+	mantissaDigits := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+		mantissaDigits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+			mantissaDigits++
+		}
+	}
+	if mantissaDigits == 0 {
+		return nil, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		expStart := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == expStart {
+			return nil, false
+		}
+	}
+	if i != len(s) {
+		return nil, false
+	}
+
+	// Out of range values become INF, like in PHP.
+	v, _ := strconv.ParseFloat(s, 64)
+	return FloatValue(v), true
+}
+
+func digitValue(ch byte) (int, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0'), true
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a') + 10, true
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A') + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,177 @@
+package constant
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// isSpace reports whether ch is a whitespace char that PHP permits
+// around numeric strings.
+func isSpace(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// scanNumber parses the longest numeric prefix of s.
+//
+// Returned n is the number of bytes consumed, including the leading whitespace.
+// If s has no numeric prefix, n is 0 and v is nil.
+// Integral values that don't fit into int64 are returned as FloatValue.
+func scanNumber(s string) (v Value, n int) {
+	i := 0
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+		digits++
+	}
+	isFloat := false
+	if i < len(s) && s[i] == '.' {
+		j := i + 1
+		fracDigits := 0
+		for j < len(s) && isDigit(s[j]) {
+			j++
+			fracDigits++
+		}
+		if digits != 0 || fracDigits != 0 {
+			isFloat = true
+			digits += fracDigits
+			i = j
+		}
+	}
+	if digits == 0 {
+		return nil, 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			isFloat = true
+			i = j
+		}
+	}
+
+	lit := s[start:i]
+	if !isFloat {
+		if v, err := strconv.ParseInt(lit, 10, 64); err == nil {
+			return IntValue(v), i
+		}
+	}
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		// Out of range errors still give us ±Inf, which is what PHP does.
+		if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrRange {
+			return nil, 0
+		}
+	}
+	return FloatValue(f), i
+}
+
+// parseNumericString returns the numeric value of s if s is a numeric string.
+//
+// PHP 8 permits trailing whitespace in numeric strings, PHP 7 does not.
+func parseNumericString(s string) (Value, bool) {
+	v, n := scanNumber(s)
+	if v == nil {
+		return nil, false
+	}
+	if TargetVersion >= PHP8 {
+		for n < len(s) && isSpace(s[n]) {
+			n++
+		}
+	}
+	if n != len(s) {
+		return nil, false
+	}
+	return v, true
+}
+
+// NumericString returns the numeric value of s if s is a numeric string,
+// like "10" or " 1.5e3". Loose comparisons treat such strings as numbers.
+func NumericString(s StringValue) (Value, bool) {
+	return parseNumericString(string(s))
+}
+
+// stringToNumber converts s to a number the way arithmetic
+// operators do: only the leading numeric part is considered and
+// strings without such a prefix are treated as 0.
+func stringToNumber(s string) Value {
+	v, _ := scanNumber(s)
+	if v == nil {
+		return IntValue(0)
+	}
+	return v
+}
+
+// formatFloat converts f to string the same way PHP does it
+// for the (string) conversion with default precision=14.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NAN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	case f == 0:
+		if math.Signbit(f) {
+			return "-0"
+		}
+		return "0"
+	}
+
+	const precision = 14
+
+	// Gives us "-d.ddddddddddddde±dd" form.
+	s := strconv.FormatFloat(f, 'e', precision-1, 64)
+	sign := ""
+	if s[0] == '-' {
+		sign = "-"
+		s = s[1:]
+	}
+	epos := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[epos+1:])
+	digits := strings.TrimRight(s[:1]+s[2:epos], "0")
+
+	if exp < -4 || exp >= precision {
+		frac := digits[1:]
+		if frac == "" {
+			frac = "0"
+		}
+		expSign := "+"
+		if exp < 0 {
+			expSign = "-"
+			exp = -exp
+		}
+		return sign + digits[:1] + "." + frac + "E" + expSign + strconv.Itoa(exp)
+	}
+
+	switch {
+	case exp < 0:
+		return sign + "0." + strings.Repeat("0", -exp-1) + digits
+	case exp+1 >= len(digits):
+		return sign + digits + strings.Repeat("0", exp+1-len(digits))
+	default:
+		return sign + digits[:exp+1] + "." + digits[exp+1:]
+	}
+}
@@ -0,0 +1,139 @@
+package constant
+
+import (
+	"math"
+)
+
+// Predefined returns a value of the predefined PHP constant, like PHP_INT_MAX.
+// name is a case-sensitive constant name without namespace qualifier.
+//
+// Platform-dependent constants are given for 64-bit Unix systems.
+func Predefined(name string) (Value, bool) {
+	if v, ok := predefinedByVersion[TargetVersion][name]; ok {
+		return v, true
+	}
+	v, ok := predefined[name]
+	return v, ok
+}
+
+// predefinedByVersion holds constants whose values depend on the PHP version.
+var predefinedByVersion = map[Version]map[string]Value{
+	PHP7: {
+		"PHP_MAJOR_VERSION": IntValue(7),
+	},
+	PHP8: {
+		"PHP_MAJOR_VERSION": IntValue(8),
+	},
+}
+
+var predefined = map[string]Value{
+	"PHP_INT_MAX":       IntValue(math.MaxInt64),
+	"PHP_INT_MIN":       IntValue(math.MinInt64),
+	"PHP_INT_SIZE":      IntValue(8),
+	"PHP_FLOAT_EPSILON": FloatValue(2.220446049250313e-16),
+	"PHP_FLOAT_MAX":     FloatValue(math.MaxFloat64),
+	"PHP_FLOAT_MIN":     FloatValue(2.2250738585072014e-308),
+	"PHP_FLOAT_DIG":     IntValue(15),
+	"PHP_EOL":           StringValue("\n"),
+
+	"DIRECTORY_SEPARATOR": StringValue("/"),
+	"PATH_SEPARATOR":      StringValue(":"),
+
+	"NAN": FloatValue(math.NaN()),
+	"INF": FloatValue(math.Inf(1)),
+
+	"M_PI":       FloatValue(math.Pi),
+	"M_PI_2":     FloatValue(math.Pi / 2),
+	"M_PI_4":     FloatValue(math.Pi / 4),
+	"M_1_PI":     FloatValue(1 / math.Pi),
+	"M_2_PI":     FloatValue(2 / math.Pi),
+	"M_E":        FloatValue(math.E),
+	"M_LOG2E":    FloatValue(math.Log2E),
+	"M_LOG10E":   FloatValue(math.Log10E),
+	"M_LN2":      FloatValue(math.Ln2),
+	"M_LN10":     FloatValue(math.Ln10),
+	"M_SQRT2":    FloatValue(math.Sqrt2),
+	"M_SQRT1_2":  FloatValue(1 / math.Sqrt2),
+	"M_SQRT3":    FloatValue(1.7320508075688772),
+	"M_SQRTPI":   FloatValue(math.SqrtPi),
+	"M_2_SQRTPI": FloatValue(2 / math.SqrtPi),
+	"M_EULER":    FloatValue(0.5772156649015329),
+
+	"E_ERROR":             IntValue(1),
+	"E_WARNING":           IntValue(2),
+	"E_PARSE":             IntValue(4),
+	"E_NOTICE":            IntValue(8),
+	"E_CORE_ERROR":        IntValue(16),
+	"E_CORE_WARNING":      IntValue(32),
+	"E_COMPILE_ERROR":     IntValue(64),
+	"E_COMPILE_WARNING":   IntValue(128),
+	"E_USER_ERROR":        IntValue(256),
+	"E_USER_WARNING":      IntValue(512),
+	"E_USER_NOTICE":       IntValue(1024),
+	"E_STRICT":            IntValue(2048),
+	"E_RECOVERABLE_ERROR": IntValue(4096),
+	"E_DEPRECATED":        IntValue(8192),
+	"E_USER_DEPRECATED":   IntValue(16384),
+	"E_ALL":               IntValue(32767),
+
+	"SORT_REGULAR":       IntValue(0),
+	"SORT_NUMERIC":       IntValue(1),
+	"SORT_STRING":        IntValue(2),
+	"SORT_DESC":          IntValue(3),
+	"SORT_ASC":           IntValue(4),
+	"SORT_LOCALE_STRING": IntValue(5),
+	"SORT_NATURAL":       IntValue(6),
+	"SORT_FLAG_CASE":     IntValue(8),
+
+	"COUNT_NORMAL":    IntValue(0),
+	"COUNT_RECURSIVE": IntValue(1),
+
+	"STR_PAD_LEFT":  IntValue(strPadLeft),
+	"STR_PAD_RIGHT": IntValue(strPadRight),
+	"STR_PAD_BOTH":  IntValue(strPadBoth),
+
+	"PHP_ROUND_HALF_UP":   IntValue(1),
+	"PHP_ROUND_HALF_DOWN": IntValue(2),
+	"PHP_ROUND_HALF_EVEN": IntValue(3),
+	"PHP_ROUND_HALF_ODD":  IntValue(4),
+
+	"ARRAY_FILTER_USE_BOTH": IntValue(1),
+	"ARRAY_FILTER_USE_KEY":  IntValue(2),
+
+	"JSON_HEX_TAG":                    IntValue(1),
+	"JSON_HEX_AMP":                    IntValue(2),
+	"JSON_HEX_APOS":                   IntValue(4),
+	"JSON_HEX_QUOT":                   IntValue(8),
+	"JSON_FORCE_OBJECT":               IntValue(16),
+	"JSON_NUMERIC_CHECK":              IntValue(32),
+	"JSON_UNESCAPED_SLASHES":          IntValue(64),
+	"JSON_PRETTY_PRINT":               IntValue(128),
+	"JSON_UNESCAPED_UNICODE":          IntValue(256),
+	"JSON_PARTIAL_OUTPUT_ON_ERROR":    IntValue(512),
+	"JSON_PRESERVE_ZERO_FRACTION":     IntValue(1024),
+	"JSON_UNESCAPED_LINE_TERMINATORS": IntValue(2048),
+	"JSON_OBJECT_AS_ARRAY":            IntValue(1),
+	"JSON_BIGINT_AS_STRING":           IntValue(2),
+	"JSON_INVALID_UTF8_IGNORE":        IntValue(1048576),
+	"JSON_INVALID_UTF8_SUBSTITUTE":    IntValue(2097152),
+	"JSON_THROW_ON_ERROR":             IntValue(4194304),
+	"JSON_ERROR_NONE":                 IntValue(0),
+
+	"PREG_PATTERN_ORDER":        IntValue(1),
+	"PREG_SET_ORDER":            IntValue(2),
+	"PREG_OFFSET_CAPTURE":       IntValue(256),
+	"PREG_UNMATCHED_AS_NULL":    IntValue(512),
+	"PREG_SPLIT_NO_EMPTY":       IntValue(1),
+	"PREG_SPLIT_DELIM_CAPTURE":  IntValue(2),
+	"PREG_SPLIT_OFFSET_CAPTURE": IntValue(4),
+
+	"ENT_NOQUOTES":   IntValue(0),
+	"ENT_COMPAT":     IntValue(2),
+	"ENT_QUOTES":     IntValue(3),
+	"ENT_IGNORE":     IntValue(4),
+	"ENT_SUBSTITUTE": IntValue(8),
+	"ENT_HTML401":    IntValue(0),
+	"ENT_XML1":       IntValue(16),
+	"ENT_XHTML":      IntValue(32),
+	"ENT_HTML5":      IntValue(48),
+}
@@ -0,0 +1,214 @@
+package constant
+
+import (
+	"strconv"
+	"strings"
+)
+
+// sprintfSpec is a parsed sprintf conversion specification.
+type sprintfSpec struct {
+	leftAlign bool
+	plusSign  bool
+	pad       byte
+	width     int
+	precision int // -1 if not specified
+	verb      byte
+}
+
+// sprintf implements PHP sprintf function.
+//
+// Returns false if format is invalid, there are not enough args
+// or the result is longer than maxStringLen.
+func sprintf(format string, args []Value) (string, bool) {
+	var out strings.Builder
+	argIndex := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i == len(format) {
+			return "", false
+		}
+		if format[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		// Optional argnum: "%1$s".
+		arg := argIndex
+		explicitArg := false
+		if j := skipDigits(format, i); j != i && j < len(format) && format[j] == '$' {
+			n, _ := strconv.Atoi(format[i:j])
+			if n == 0 {
+				return "", false
+			}
+			arg = n - 1
+			explicitArg = true
+			i = j + 1
+		}
+
+		spec := sprintfSpec{pad: ' ', precision: -1}
+	flags:
+		for ; i < len(format); i++ {
+			switch format[i] {
+			case '-':
+				spec.leftAlign = true
+			case '+':
+				spec.plusSign = true
+			case ' ', '0':
+				spec.pad = format[i]
+			case '\'':
+				if i+1 == len(format) {
+					return "", false
+				}
+				spec.pad = format[i+1]
+				i++
+			default:
+				break flags
+			}
+		}
+		if j := skipDigits(format, i); j != i {
+			spec.width, _ = strconv.Atoi(format[i:j])
+			i = j
+		}
+		if i < len(format) && format[i] == '.' {
+			j := skipDigits(format, i+1)
+			spec.precision, _ = strconv.Atoi(format[i+1 : j])
+			i = j
+		}
+		if i == len(format) || arg >= len(args) || spec.width > maxStringLen {
+			return "", false
+		}
+		spec.verb = format[i]
+		if !explicitArg {
+			argIndex++
+		}
+
+		s, ok := spec.format(args[arg])
+		if !ok || out.Len()+len(s) > maxStringLen {
+			return "", false
+		}
+		out.WriteString(s)
+	}
+	if out.Len() > maxStringLen {
+		return "", false
+	}
+	return out.String(), true
+}
+
+func skipDigits(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// maxFloatPrecision is a PHP limit for the floats precision.
+// Greater precision is truncated with a notice.
+const maxFloatPrecision = 53
+
+func (spec *sprintfSpec) format(x Value) (string, bool) {
+	var s string
+	switch spec.verb {
+	case 's':
+		v, ok := stringArg(x)
+		if !ok {
+			return "", false
+		}
+		s = v
+		if spec.precision >= 0 && spec.precision < len(s) {
+			s = s[:spec.precision]
+		}
+		return spec.padString(s), true
+
+	case 'd', 'u', 'c', 'x', 'X', 'o', 'b':
+		n, ok := toIntOperand(x)
+		if !ok {
+			return "", false
+		}
+		switch spec.verb {
+		case 'd':
+			s = strconv.FormatInt(int64(n), 10)
+			if spec.plusSign && n >= 0 {
+				s = "+" + s
+			}
+			return spec.padNumber(s), true
+		case 'u':
+			s = strconv.FormatUint(uint64(n), 10)
+		case 'c':
+			// Width and padding are ignored for %c.
+			return string([]byte{byte(n)}), true
+		case 'x':
+			s = strconv.FormatUint(uint64(n), 16)
+		case 'X':
+			s = strings.ToUpper(strconv.FormatUint(uint64(n), 16))
+		case 'o':
+			s = strconv.FormatUint(uint64(n), 8)
+		case 'b':
+			s = strconv.FormatUint(uint64(n), 2)
+		}
+		return spec.padNumber(s), true
+
+	case 'f', 'F', 'e', 'E':
+		v, ok := toNumber(x)
+		if !ok {
+			return "", false
+		}
+		f := toFloat64(v)
+		prec := spec.precision
+		if prec == -1 {
+			prec = 6
+		}
+		if prec > maxFloatPrecision {
+			prec = maxFloatPrecision
+		}
+		if spec.verb == 'f' || spec.verb == 'F' {
+			s = strconv.FormatFloat(f, 'f', prec, 64)
+		} else {
+			s = formatExp(strconv.FormatFloat(f, byte(spec.verb), prec, 64))
+		}
+		if spec.plusSign && f >= 0 {
+			s = "+" + s
+		}
+		return spec.padNumber(s), true
+	}
+
+	return "", false
+}
+
+// formatExp converts Go exponent format to PHP one: "1.5e+01" => "1.5e+1".
+func formatExp(s string) string {
+	i := strings.LastIndexAny(s, "eE")
+	if i == -1 || i+2 >= len(s) {
+		return s
+	}
+	exp := strings.TrimLeft(s[i+2:], "0")
+	if exp == "" {
+		exp = "0"
+	}
+	return s[:i+2] + exp
+}
+
+func (spec *sprintfSpec) padString(s string) string {
+	if len(s) >= spec.width {
+		return s
+	}
+	padding := strings.Repeat(string(spec.pad), spec.width-len(s))
+	if spec.leftAlign {
+		return s + padding
+	}
+	return padding + s
+}
+
+// padNumber is like padString, but zero padding is inserted after the sign.
+func (spec *sprintfSpec) padNumber(s string) string {
+	if spec.pad != '0' || spec.leftAlign || len(s) >= spec.width || s == "" {
+		return spec.padString(s)
+	}
+	if s[0] == '-' || s[0] == '+' {
+		return s[:1] + strings.Repeat("0", spec.width-len(s)) + s[1:]
+	}
+	return spec.padString(s)
+}
@@ -4,6 +4,8 @@ package constant
 var UnaryOps = map[string]func(Value) Value{
 	"!": Not,
 	"-": Neg,
+	"+": Plus,
+	"~": BitNot,
 }
 
 // Not performs logical "!".
@@ -17,16 +19,31 @@ func Not(x Value) Value {
 
 // Neg performs arithmetic unary "-".
 func Neg(x Value) Value {
+	return Mul(x, IntValue(-1))
+}
+
+// Plus performs arithmetic unary "+".
+// It converts its operand to number.
+func Plus(x Value) Value {
+	return Mul(x, IntValue(1))
+}
+
+// BitNot performs bitwise "~".
+func BitNot(x Value) Value {
 	switch x := x.(type) {
 	case IntValue:
-		return -x
+		return ^x
 	case FloatValue:
-		return -x
-	case BoolValue:
-		if x {
-			return IntValue(-1)
+		v, ok := floatToInt(float64(x))
+		if ok {
+			return ^v
+		}
+	case StringValue:
+		res := []byte(x)
+		for i := range res {
+			res[i] = ^res[i]
 		}
-		return IntValue(0)
+		return StringValue(res)
 	}
 	return UnknownValue{}
 }
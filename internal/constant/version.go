@@ -0,0 +1,43 @@
+package constant
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is a PHP language version.
+//
+// Some operations, like string to number comparison, behave
+// differently depending on the PHP version.
+type Version int
+
+// Supported PHP language versions.
+const (
+	PHP7 Version = 7
+	PHP8 Version = 8
+)
+
+// TargetVersion is a PHP version which semantics is used by
+// version-dependent operations.
+//
+// Implements flag.Value, so it can be bound to a command-line flag.
+var TargetVersion = PHP7
+
+// String returns version string representation, like "7".
+func (v *Version) String() string {
+	return fmt.Sprint(int(*v))
+}
+
+// Set parses version from s.
+// Both major-only ("8") and major.minor ("8.1") forms are accepted.
+func (v *Version) Set(s string) error {
+	switch {
+	case s == "7" || strings.HasPrefix(s, "7."):
+		*v = PHP7
+	case s == "8" || strings.HasPrefix(s, "8."):
+		*v = PHP8
+	default:
+		return fmt.Errorf("unsupported PHP version %q (expected 7 or 8)", s)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Package interval implements sets of real numbers that are
+// represented as unions of the disjoint intervals.
+//
+// Sets are used to describe value ranges of the numeric
+// expressions, like "$x > 5 && $x != 10".
+//
+// Both infinities are included into the number line,
+// since PHP floats can hold them. NaN values are not supported.
+package interval
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Interval is a connected set of numbers between Lo and Hi.
+type Interval struct {
+	Lo, Hi float64
+
+	// LoOpen and HiOpen report whether the bounds are excluded.
+	LoOpen, HiOpen bool
+}
+
+func (iv Interval) isEmpty() bool {
+	return iv.Lo > iv.Hi || (iv.Lo == iv.Hi && (iv.LoOpen || iv.HiOpen))
+}
+
+func (iv Interval) String() string {
+	var sb strings.Builder
+	if iv.LoOpen {
+		sb.WriteByte('(')
+	} else {
+		sb.WriteByte('[')
+	}
+	sb.WriteString(formatBound(iv.Lo))
+	sb.WriteString(", ")
+	sb.WriteString(formatBound(iv.Hi))
+	if iv.HiOpen {
+		sb.WriteByte(')')
+	} else {
+		sb.WriteByte(']')
+	}
+	return sb.String()
+}
+
+func formatBound(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+inf"
+	case math.IsInf(x, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// Set is a union of the disjoint intervals.
+//
+// Sets are immutable, all operations return new sets.
+// The zero value is an empty set.
+type Set struct {
+	// ivs are ordered by their lower bounds.
+	// Adjacent intervals never overlap or touch.
+	ivs []Interval
+}
+
+// Empty returns an empty set.
+func Empty() Set { return Set{} }
+
+// Full returns a set of all numbers.
+func Full() Set {
+	return Set{ivs: []Interval{{Lo: math.Inf(-1), Hi: math.Inf(1)}}}
+}
+
+// Point returns a set that contains only x.
+func Point(x float64) Set { return Set{ivs: []Interval{{Lo: x, Hi: x}}} }
+
+// NotPoint returns a set of all numbers except x.
+func NotPoint(x float64) Set { return Point(x).Complement() }
+
+// Less returns a set of numbers that are less than x.
+func Less(x float64) Set { return New(Interval{Lo: math.Inf(-1), Hi: x, HiOpen: true}) }
+
+// LessOrEqual returns a set of numbers that are less than or equal to x.
+func LessOrEqual(x float64) Set { return New(Interval{Lo: math.Inf(-1), Hi: x}) }
+
+// Greater returns a set of numbers that are greater than x.
+func Greater(x float64) Set { return New(Interval{Lo: x, LoOpen: true, Hi: math.Inf(1)}) }
+
+// GreaterOrEqual returns a set of numbers that are greater than or equal to x.
+func GreaterOrEqual(x float64) Set { return New(Interval{Lo: x, Hi: math.Inf(1)}) }
+
+// New returns a union of the given intervals.
+func New(ivs ...Interval) Set {
+	return normalize(append([]Interval(nil), ivs...))
+}
+
+// Intervals returns the set intervals ordered by their lower bounds.
+func (s Set) Intervals() []Interval {
+	return append([]Interval(nil), s.ivs...)
+}
+
+// IsEmpty reports whether s contains no numbers.
+func (s Set) IsEmpty() bool { return len(s.ivs) == 0 }
+
+// IsFull reports whether s contains all numbers.
+func (s Set) IsFull() bool { return s.Equal(Full()) }
+
+// Equal reports whether s and t contain the same numbers.
+func (s Set) Equal(t Set) bool {
+	if len(s.ivs) != len(t.ivs) {
+		return false
+	}
+	for i := range s.ivs {
+		if s.ivs[i] != t.ivs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SubsetOf reports whether every number of s is contained in t.
+func (s Set) SubsetOf(t Set) bool {
+	return s.Intersect(t.Complement()).IsEmpty()
+}
+
+// Union returns a set of numbers that are contained in s or t.
+func (s Set) Union(t Set) Set {
+	ivs := make([]Interval, 0, len(s.ivs)+len(t.ivs))
+	ivs = append(ivs, s.ivs...)
+	ivs = append(ivs, t.ivs...)
+	return normalize(ivs)
+}
+
+// Intersect returns a set of numbers that are contained in both s and t.
+func (s Set) Intersect(t Set) Set {
+	var ivs []Interval
+	for _, x := range s.ivs {
+		for _, y := range t.ivs {
+			iv := x
+			if y.Lo > iv.Lo || (y.Lo == iv.Lo && y.LoOpen) {
+				iv.Lo, iv.LoOpen = y.Lo, y.LoOpen
+			}
+			if y.Hi < iv.Hi || (y.Hi == iv.Hi && y.HiOpen) {
+				iv.Hi, iv.HiOpen = y.Hi, y.HiOpen
+			}
+			if !iv.isEmpty() {
+				ivs = append(ivs, iv)
+			}
+		}
+	}
+	return normalize(ivs)
+}
+
+// Complement returns a set of numbers that are not contained in s.
+func (s Set) Complement() Set {
+	var ivs []Interval
+	gap := Interval{Lo: math.Inf(-1)}
+	for _, iv := range s.ivs {
+		gap.Hi, gap.HiOpen = iv.Lo, !iv.LoOpen
+		ivs = append(ivs, gap)
+		gap = Interval{Lo: iv.Hi, LoOpen: !iv.HiOpen}
+	}
+	gap.Hi = math.Inf(1)
+	ivs = append(ivs, gap)
+	return normalize(ivs)
+}
+
+func (s Set) String() string {
+	if s.IsEmpty() {
+		return "{}"
+	}
+	parts := make([]string, len(s.ivs))
+	for i, iv := range s.ivs {
+		parts[i] = iv.String()
+	}
+	return strings.Join(parts, " U ")
+}
+
+// normalize sorts ivs and merges the overlapping intervals.
+func normalize(ivs []Interval) Set {
+	// Insertion sort: sets are usually tiny.
+	for i := 1; i < len(ivs); i++ {
+		for j := i; j > 0 && lowerBoundLess(ivs[j], ivs[j-1]); j-- {
+			ivs[j], ivs[j-1] = ivs[j-1], ivs[j]
+		}
+	}
+
+	var res []Interval
+	for _, iv := range ivs {
+		if iv.isEmpty() {
+			continue
+		}
+		if len(res) == 0 {
+			res = append(res, iv)
+			continue
+		}
+		last := &res[len(res)-1]
+		touches := iv.Lo < last.Hi || (iv.Lo == last.Hi && !(iv.LoOpen && last.HiOpen))
+		if !touches {
+			res = append(res, iv)
+			continue
+		}
+		if iv.Hi > last.Hi || (iv.Hi == last.Hi && !iv.HiOpen) {
+			last.Hi, last.HiOpen = iv.Hi, iv.HiOpen
+		}
+	}
+	return Set{ivs: res}
+}
+
+func lowerBoundLess(x, y Interval) bool {
+	if x.Lo != y.Lo {
+		return x.Lo < y.Lo
+	}
+	return !x.LoOpen && y.LoOpen
+}
@@ -0,0 +1,71 @@
+package interval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSetOps(t *testing.T) {
+	tests := []struct {
+		set  Set
+		want string
+	}{
+		{Empty(), "{}"},
+		{Full(), "[-inf, +inf]"},
+		{Point(5), "[5, 5]"},
+		{NotPoint(5), "[-inf, 5) U (5, +inf]"},
+		{Less(5), "[-inf, 5)"},
+		{GreaterOrEqual(1.5), "[1.5, +inf]"},
+
+		{Less(5).Intersect(Greater(3)), "(3, 5)"},
+		{Less(3).Intersect(Greater(5)), "{}"},
+		{Less(5).Intersect(Greater(5)), "{}"},
+		{LessOrEqual(5).Intersect(GreaterOrEqual(5)), "[5, 5]"},
+		{Greater(5).Intersect(Greater(3)), "(5, +inf]"},
+		{NotPoint(1).Intersect(NotPoint(2)), "[-inf, 1) U (1, 2) U (2, +inf]"},
+		{NotPoint(1).Intersect(Point(1)), "{}"},
+
+		{Less(5).Union(GreaterOrEqual(5)), "[-inf, +inf]"},
+		{Less(5).Union(Greater(5)), "[-inf, 5) U (5, +inf]"},
+		{Less(3).Union(Less(5)), "[-inf, 5)"},
+		{Point(1).Union(Point(3)).Union(Point(2)), "[1, 1] U [2, 2] U [3, 3]"},
+		{NotPoint(1).Union(NotPoint(2)), "[-inf, +inf]"},
+		{Greater(1).Intersect(Less(2)).Union(Point(2)), "(1, 2]"},
+
+		{Full().Complement(), "{}"},
+		{Empty().Complement(), "[-inf, +inf]"},
+		{Less(5).Complement(), "[5, +inf]"},
+		{Point(1).Union(Point(3)).Complement(), "[-inf, 1) U (1, 3) U (3, +inf]"},
+		{Point(math.Inf(1)).Complement(), "[-inf, +inf)"},
+	}
+
+	for _, test := range tests {
+		if have := test.set.String(); have != test.want {
+			t.Errorf("have %s, want %s", have, test.want)
+		}
+	}
+}
+
+func TestSetPredicates(t *testing.T) {
+	if !Less(5).Union(GreaterOrEqual(5)).IsFull() {
+		t.Errorf("x < 5 || x >= 5 is expected to be full")
+	}
+	if Less(5).Union(Greater(5)).IsFull() {
+		t.Errorf("x < 5 || x > 5 is not expected to be full")
+	}
+	if !Greater(5).SubsetOf(Greater(3)) {
+		t.Errorf("x > 5 is expected to be a subset of x > 3")
+	}
+	if Greater(3).SubsetOf(Greater(5)) {
+		t.Errorf("x > 3 is not expected to be a subset of x > 5")
+	}
+	if !Point(5).SubsetOf(NotPoint(4)) {
+		t.Errorf("x == 5 is expected to be a subset of x != 4")
+	}
+	if !Empty().SubsetOf(Point(1)) {
+		t.Errorf("empty set is expected to be a subset of any set")
+	}
+	if !Less(5).Equal(New(Interval{Lo: math.Inf(-1), Hi: 4}, Interval{Lo: 4, Hi: 5, HiOpen: true})) {
+		t.Errorf("touching intervals are expected to be merged")
+	}
+}
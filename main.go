@@ -1,29 +1,84 @@
 package main
 
 import (
+	"flag"
+	"log"
+	"os"
+
 	"github.com/VKCOM/noverify/src/cmd"
 	"github.com/VKCOM/noverify/src/linter"
-	"github.com/VKCOM/noverify/src/meta"
+	"github.com/quasilyte/php-critic/internal/constant"
 	"github.com/z7zmey/php-parser/node"
 )
 
+var (
+	listChecks = flag.String("list-checks", "", "Print all checks in the given format (text, json or markdown) and exit")
+	configPath = flag.String("config", "", "Path to the config file (by default, .php-critic.yml, .php-critic.yaml or .php-critic.json is looked up in the analyzed paths and their parents)")
+	fixMode    = flag.Bool("fix", false, "Apply the fixes of the fixable reports in place")
+	diffMode   = flag.Bool("diff", false, "Print the fixes of the fixable reports as a unified diff")
+)
+
 func init() {
-	mi := &metainfoExt{
-		constValue: map[string]node.Node{},
-		st:         &meta.ClassParseState{},
+	flag.Var(&constant.TargetVersion, "php-version", "Target PHP version (7 or 8) for version-dependent semantics")
+
+	clones := &cloneIndex{}
+	flag.IntVar(&clones.minGroup, "clone-min-group", 2, "Minimal number of functions with duplicated bodies to report (0 disables the clone detection)")
+	flag.IntVar(&clones.minSize, "clone-min-size", 40, "Minimal function body size in AST nodes for the clone detection")
+
+	idx := &metaIndex{
+		constValue:      map[string]node.Node{},
+		classConstValue: map[string]map[string]*constInit{},
+		funcSummaries:   map[string]*funcSummary{},
+		funcPurity:      map[string]bool{},
+	}
+	newMetainfoExt := func(ctxt *linter.BlockContext) *metainfoExt {
+		// The visited block starts at the current root walker state.
+		st := *ctxt.ClassParseState()
+		return &metainfoExt{
+			metaIndex: idx,
+			ctxt:      ctxt,
+			st:        &st,
+		}
 	}
+	linter.RegisterRootChecker(func(ctxt *linter.RootContext) linter.RootChecker {
+		return newMetainfoRootExt(ctxt, idx)
+	})
+	linter.RegisterRootChecker(func(ctxt *linter.RootContext) linter.RootChecker {
+		return &cloneChecker{ctxt: ctxt, idx: clones}
+	})
 	linter.RegisterBlockChecker(func(ctxt *linter.BlockContext) linter.BlockChecker {
-		mi.ctxt = ctxt
-		return mi
+		return newMetainfoExt(ctxt)
 	})
 	linter.RegisterBlockChecker(func(ctxt *linter.BlockContext) linter.BlockChecker {
 		return &blockChecker{
-			ctxt: ctxt,
-			mi:   mi,
+			ctxt:        ctxt,
+			mi:          newMetainfoExt(ctxt),
+			rangeChains: map[node.Node]bool{},
 		}
 	})
 }
 
 func main() {
+	// cmd.Main parses the flags too, but it doesn't know about our flags.
+	flag.Parse()
+	if *listChecks != "" {
+		if err := printChecks(os.Stdout, *listChecks); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := loadConfigs(*configPath, flag.Args()); err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	if cachedIndexing() {
+		log.Printf("-cache-dir is set: class constants folding, function purity analysis and clone detection are disabled")
+	}
+	if *fixMode || *diffMode {
+		if err := runFixes(os.Stdout, flag.Args(), *fixMode, *diffMode); err != nil {
+			log.Fatalf("fix: %v", err)
+		}
+		return
+	}
+
 	cmd.Main()
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/VKCOM/noverify/src/linter"
 	"github.com/VKCOM/noverify/src/meta"
 	"github.com/VKCOM/noverify/src/state"
@@ -8,18 +11,92 @@ import (
 	"github.com/z7zmey/php-parser/node/expr"
 	"github.com/z7zmey/php-parser/node/name"
 	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/position"
 	"github.com/z7zmey/php-parser/walker"
 )
 
+// metainfoExt is a block checker that records the global constants
+// and folds the constant expressions for the other block checkers.
+//
+// Every block context has its own metainfoExt,
+// the collected information is shared through the metaIndex.
 type metainfoExt struct {
+	*metaIndex
+
 	ctxt *linter.BlockContext
 
+	// st is a class parse state of the visited block.
 	st *meta.ClassParseState
 
+	// constState is a declaration context of the class constant
+	// initializer that is being folded right now.
+	constState *meta.ClassParseState
+	// classConstFolding holds class constants that are being folded
+	// to break the recursive definitions, like "const A = self::A".
+	//
+	// Both are set only for a copy of the checker that folds the
+	// class constant initializer, the checker itself is never changed.
+	classConstFolding *constFolding
+}
+
+// constFolding is a chain of the class constants that are being folded,
+// from the innermost one to the outermost one.
+type constFolding struct {
+	init  *constInit
+	outer *constFolding
+}
+
+func (f *constFolding) contains(init *constInit) bool {
+	for ; f != nil; f = f.outer {
+		if f.init == init {
+			return true
+		}
+	}
+	return false
+}
+
+// metaIndex is the information that is shared between all checkers.
+type metaIndex struct {
 	// TODO(quasilyte): change key type to *meta.ConstantInfo?
 	// But how to get ConstantInfo by *stmt.Constant.ConstantName?
 	// solver.GetConstant seem not to work.
 	constValue map[string]node.Node
+
+	// classConstValue maps class name to its constants initializers.
+	// It's filled by metainfoRootExt during the indexing.
+	classConstValue map[string]map[string]*constInit
+	mu              sync.Mutex
+
+	// funcSummaries maps lowercased function names to their effects summaries.
+	// It's filled by metainfoRootExt during the indexing.
+	funcSummaries map[string]*funcSummary
+	// funcPurity caches isPureFunc results.
+	funcPurity map[string]bool
+}
+
+// constInit is a constant initializer expression
+// along with its declaration context.
+type constInit struct {
+	st   meta.ClassParseState
+	expr node.Node
+}
+
+// classParseState returns a state that is used to resolve
+// names during the constant folding.
+func (m *metainfoExt) classParseState() *meta.ClassParseState {
+	if m.constState != nil {
+		return m.constState
+	}
+	return m.ctxt.ClassParseState()
+}
+
+// getClassConst returns an initializer of the constName
+// that is declared inside className class.
+func (m *metaIndex) getClassConst(className, constName string) (*constInit, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	init, ok := m.classConstValue[className][constName]
+	return init, ok
 }
 
 func (m *metainfoExt) AfterEnterNode(w walker.Walkable)  {}
@@ -48,3 +125,167 @@ func (m *metainfoExt) BeforeEnterNode(w walker.Walkable) {
 		m.constValue[name] = n.Expr
 	}
 }
+
+// cachedIndexing reports whether the indexing can use the -cache-dir cache.
+//
+// Cached files meta is restored without walking the files, so the
+// php-critic indexes (function summaries, class constants and function
+// clones) would be filled only by the files that missed the cache.
+// These indexes are not used at all in this case.
+func cachedIndexing() bool {
+	return linter.CacheDir != ""
+}
+
+// fileInfoKey is a root context state key that is bound to the *fileInfo.
+const fileInfoKey = "php-critic.fileInfo"
+
+// fileInfo describes the file that is being analyzed.
+// It's shared between the root and block checkers through the root context state.
+type fileInfo struct {
+	filename string
+
+	// root is the file root node.
+	root node.Node
+	// positions and lines are parsed from the root on demand.
+	positions position.Positions
+	lines     [][]byte
+	parsed    bool
+
+	// function and method are __FUNCTION__ and __METHOD__ values
+	// for the function that is being analyzed.
+	function string
+	method   string
+
+	// fn is a function that is being analyzed.
+	fn node.Node
+	// locals are fn local variables values that are computed on demand.
+	locals localConsts
+	// propagating is set while locals are being computed.
+	propagating bool
+
+	// settings are the config settings for this file.
+	settings fileSettings
+	// suppressions are the inline suppressions of this file.
+	// They're parsed only after the indexing is complete.
+	suppressions suppressions
+}
+
+// nodePositions returns the positions of the file nodes.
+// Positions are unknown if the file can't be parsed again,
+// see parseFilePositions.
+func (info *fileInfo) nodePositions() position.Positions {
+	if !info.parsed && info.root != nil {
+		info.parsed = true
+		info.positions, info.lines, _ = parseFilePositions(info.filename, info.root)
+	}
+	return info.positions
+}
+
+// metainfoRootExt records class constants initializers and
+// the information about the file that is being analyzed.
+//
+// Class bodies are not visited by the block checkers,
+// so class constants are collected by the root checker during the indexing.
+type metainfoRootExt struct {
+	linter.RootCheckerDefaults
+
+	ctxt *linter.RootContext
+	idx  *metaIndex
+	info *fileInfo
+
+	// rootVisited is set after the file root node is visited.
+	rootVisited bool
+}
+
+func newMetainfoRootExt(ctxt *linter.RootContext, idx *metaIndex) *metainfoRootExt {
+	info := &fileInfo{
+		filename: ctxt.Filename(),
+		settings: settingsFor(ctxt.Filename()),
+	}
+	ctxt.State()[fileInfoKey] = info
+	return &metainfoRootExt{ctxt: ctxt, idx: idx, info: info}
+}
+
+func (m *metainfoRootExt) BeforeEnterNode(w walker.Walkable) {
+	if !m.rootVisited {
+		// The first visited node is the file root.
+		m.rootVisited = true
+		m.info.root = w.(node.Node)
+		if meta.IsIndexingComplete() {
+			if positions := m.info.nodePositions(); positions != nil {
+				m.info.suppressions = parseSuppressions(positions, m.info.lines, m.info.root)
+			}
+		}
+	}
+
+	st := m.ctxt.ClassParseState()
+
+	switch n := w.(type) {
+	case *stmt.Function:
+		// Functions are analyzed right after they're entered.
+		name := n.FunctionName.(*node.Identifier).Value
+		m.info.function = strings.TrimPrefix(st.Namespace+`\`+name, `\`)
+		m.info.method = m.info.function
+		m.info.fn = n
+		m.info.locals = nil
+		if !meta.IsIndexingComplete() && !cachedIndexing() {
+			m.idx.recordFuncSummary(st, st.Namespace+`\`+name, n)
+		}
+	case *stmt.ClassMethod:
+		name := n.MethodName.(*node.Identifier).Value
+		m.info.function = name
+		m.info.method = strings.TrimPrefix(st.CurrentClass, `\`) + "::" + name
+		m.info.fn = n
+		m.info.locals = nil
+	case *stmt.ClassConstList:
+		if !meta.IsIndexingComplete() && !cachedIndexing() {
+			m.recordClassConsts(st, n)
+		}
+	}
+}
+
+// AfterEnterNode resets the function info after the function is analyzed.
+//
+// The root walker doesn't visit the function children and doesn't
+// leave the function node, so it's done right after the function is entered.
+// Otherwise the root level code that is analyzed after all
+// functions would see the info of the last function.
+func (m *metainfoRootExt) AfterEnterNode(w walker.Walkable) {
+	switch w.(type) {
+	case *stmt.Function, *stmt.ClassMethod:
+		m.info.function = ""
+		m.info.method = ""
+		m.info.fn = nil
+		m.info.locals = nil
+	}
+}
+
+// AfterLeaveFile reports malformed and stale suppressions.
+// All reports of the file are already made at this point.
+func (m *metainfoRootExt) AfterLeaveFile() {
+	for _, sup := range m.info.suppressions {
+		if sup.err != "" {
+			report(m.ctxt, m.info, sup.node, "badIgnore", "bad php-critic:ignore directive: %s", sup.err)
+			continue
+		}
+		for _, checkName := range sup.checks {
+			if !sup.used[checkName] {
+				report(m.ctxt, m.info, sup.node, "staleIgnore", "stale php-critic:ignore directive: no %s reports to suppress", checkName)
+			}
+		}
+	}
+}
+
+func (m *metainfoRootExt) recordClassConsts(st *meta.ClassParseState, n *stmt.ClassConstList) {
+	m.idx.mu.Lock()
+	defer m.idx.mu.Unlock()
+	consts := m.idx.classConstValue[st.CurrentClass]
+	if consts == nil {
+		consts = make(map[string]*constInit)
+		m.idx.classConstValue[st.CurrentClass] = consts
+	}
+	for _, c := range n.Consts {
+		c := c.(*stmt.Constant)
+		consts[c.ConstantName.(*node.Identifier).Value] = &constInit{st: *st, expr: c.Expr}
+	}
+}
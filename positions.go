@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/VKCOM/noverify/src/linter"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/php7"
+	"github.com/z7zmey/php-parser/position"
+	"github.com/z7zmey/php-parser/walker"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// parseFilePositions returns the positions of the root tree nodes
+// and the lines of the file, without the line endings.
+//
+// RootContext doesn't expose the positions to the custom checkers,
+// so the file is parsed once again and the nodes of both trees
+// are matched in the walk order.
+// Returns false if the file can't be read or doesn't match the root tree,
+// like the files that are analyzed from a git commit.
+func parseFilePositions(filename string, root node.Node) (position.Positions, [][]byte, bool) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, false
+	}
+	if linter.DefaultEncoding == "windows-1251" {
+		src, err = charmap.Windows1251.NewDecoder().Bytes(src)
+		if err != nil {
+			return nil, nil, false
+		}
+	}
+	parser := php7.NewParser(bytes.NewReader(src), filename)
+	parser.Parse()
+	parsed := parser.GetRootNode()
+	if parsed == nil {
+		return nil, nil, false
+	}
+
+	nodes := walkOrder(root)
+	parsedNodes := walkOrder(parsed)
+	if len(nodes) != len(parsedNodes) {
+		return nil, nil, false
+	}
+	parsedPositions := parser.GetPositions()
+	positions := make(position.Positions, len(nodes))
+	for i, n := range nodes {
+		p := parsedNodes[i]
+		if reflect.TypeOf(n) != reflect.TypeOf(p) {
+			return nil, nil, false
+		}
+		if id, ok := n.(*node.Identifier); ok && id.Value != p.(*node.Identifier).Value {
+			return nil, nil, false
+		}
+		if pos := parsedPositions[p]; pos != nil {
+			positions[n] = pos
+		}
+	}
+	return positions, bytes.Split(src, []byte("\n")), true
+}
+
+// walkOrder returns all nodes of the root tree in the walk order.
+func walkOrder(root node.Node) []node.Node {
+	var v walkOrderCollector
+	root.Walk(&v)
+	return v.nodes
+}
+
+type walkOrderCollector struct {
+	nodes []node.Node
+}
+
+func (v *walkOrderCollector) EnterNode(w walker.Walkable) bool {
+	if n, ok := w.(node.Node); ok {
+		v.nodes = append(v.nodes, n)
+	}
+	return true
+}
+
+func (v *walkOrderCollector) GetChildrenVisitor(key string) walker.Visitor { return v }
+func (v *walkOrderCollector) LeaveNode(w walker.Walkable)                  {}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/php7"
+)
+
+func TestParseFilePositions(t *testing.T) {
+	src := "<?php\nfunction f() {}\n\nfunction g() {}\n"
+	parser := php7.NewParser(bytes.NewReader([]byte(src)), "positions.php")
+	parser.Parse()
+	root := parser.GetRootNode()
+	var funcs []node.Node
+	for _, n := range walkOrder(root) {
+		if _, ok := n.(*stmt.Function); ok {
+			funcs = append(funcs, n)
+		}
+	}
+
+	if _, _, ok := parseFilePositions("positions.php", root); ok {
+		t.Errorf("missing file positions are parsed")
+	}
+
+	if err := ioutil.WriteFile("positions.php", []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	positions, lines, ok := parseFilePositions("positions.php", root)
+	if !ok {
+		t.Fatalf("positions are not parsed")
+	}
+	if len(lines) != 5 {
+		t.Errorf("lines count mismatch: have %d, want 5", len(lines))
+	}
+	for i, want := range []int{2, 4} {
+		if have := positions[funcs[i]].StartLine; have != want {
+			t.Errorf("function %d line mismatch: have %d, want %d", i, have, want)
+		}
+	}
+
+	modified := "<?php\nfunction f() {}\n\nfunction h() {}\n"
+	if err := ioutil.WriteFile("positions.php", []byte(modified), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := parseFilePositions("positions.php", root); ok {
+		t.Errorf("positions of the modified file are parsed")
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/VKCOM/noverify/src/meta"
+	"github.com/quasilyte/php-critic/internal/constant"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/assign"
+	"github.com/z7zmey/php-parser/node/stmt"
+	"github.com/z7zmey/php-parser/walker"
+)
+
+// builtinPurity tells whether a builtin function is pure.
+//
+// Functions that are registered in the constant package are pure too.
+// Functions that are not listed here are considered to be impure,
+// unless they're user functions with inferred pure summary.
+var builtinPurity = map[string]bool{
+	`\array_combine`:      true,
+	`\array_diff`:         true,
+	`\array_diff_key`:     true,
+	`\array_fill`:         true,
+	`\array_fill_keys`:    true,
+	`\array_flip`:         true,
+	`\array_intersect`:    true,
+	`\array_key_first`:    true,
+	`\array_key_last`:     true,
+	`\array_reverse`:      true,
+	`\array_search`:       true,
+	`\array_slice`:        true,
+	`\array_sum`:          true,
+	`\array_product`:      true,
+	`\array_unique`:       true,
+	`\base64_decode`:      true,
+	`\base64_encode`:      true,
+	`\bin2hex`:            true,
+	`\crc32`:              true,
+	`\explode`:            true,
+	`\hex2bin`:            true,
+	`\html_entity_decode`: true,
+	`\htmlspecialchars`:   true,
+	`\is_callable`:        true,
+	`\is_object`:          true,
+	`\is_iterable`:        true,
+	`\is_countable`:       true,
+	`\json_encode`:        true,
+	`\lcg_value`:          false,
+	`\mb_strlen`:          true,
+	`\mb_strtolower`:      true,
+	`\mb_strtoupper`:      true,
+	`\mb_substr`:          true,
+	`\md5`:                true,
+	`\microtime`:          false,
+	`\mt_rand`:            false,
+	`\nl2br`:              true,
+	`\number_format`:      true,
+	`\preg_quote`:         true,
+	`\rand`:               false,
+	`\random_bytes`:       false,
+	`\random_int`:         false,
+	`\range`:              true,
+	`\rawurlencode`:       true,
+	`\sha1`:               true,
+	`\str_split`:          true,
+	`\strcasecmp`:         true,
+	`\strncasecmp`:        true,
+	`\strncmp`:            true,
+	`\strstr`:             true,
+	`\strtr`:              true,
+	`\substr_count`:       true,
+	`\time`:               false,
+	`\uniqid`:             false,
+	`\urlencode`:          true,
+	`\vsprintf`:           true,
+	`\wordwrap`:           true,
+}
+
+// funcSummary describes the effects of a user function body.
+type funcSummary struct {
+	st meta.ClassParseState
+
+	// impure is set if the function body has its own side effects.
+	impure bool
+
+	// calls are the function calls that are made from the body.
+	// Function is pure only if all of them are pure.
+	calls []*expr.FunctionCall
+}
+
+// recordFuncSummary infers fn summary and binds it to the fqn.
+func (m *metaIndex) recordFuncSummary(st *meta.ClassParseState, fqn string, fn *stmt.Function) {
+	summary := &funcSummary{st: *st}
+	switch {
+	case len(fn.Stmts) == 0:
+		// Functions without statements are usually declarations from the stubs.
+		summary.impure = true
+	case fn.ReturnsRef:
+		summary.impure = true
+	default:
+		v := effectsVisitor{summary: summary, params: map[string]bool{}}
+		for _, p := range fn.Params {
+			p := p.(*node.Parameter)
+			if p.ByRef {
+				summary.impure = true
+			}
+			if name, ok := localVarName(p.Variable.(*expr.Variable)); ok {
+				v.params[name] = true
+			}
+		}
+		for _, s := range fn.Stmts {
+			s.Walk(&v)
+		}
+	}
+
+	m.mu.Lock()
+	m.funcSummaries[strings.ToLower(fqn)] = summary
+	// Purity depends on the callee summaries, so the results
+	// that are cached by the previous passes are outdated.
+	if len(m.funcPurity) != 0 {
+		m.funcPurity = map[string]bool{}
+	}
+	m.mu.Unlock()
+}
+
+// isPureFunc reports whether the fully-qualified function is pure.
+//
+// Pure function has no side effects and its result depends only
+// on its arguments, so calling it twice with the same args gives
+// the same result.
+func (m *metaIndex) isPureFunc(fqn string) bool {
+	return m.checkPureFunc(fqn, nil)
+}
+
+// checkPureFunc is isPureFunc that tracks the functions whose purity
+// is being computed by the current call chain inside the visiting set.
+//
+// Recursive calls are considered to be impure, so all functions
+// of a calls cycle are impure. Hence the results that depend on
+// the visiting functions are final too and can be cached.
+func (m *metaIndex) checkPureFunc(fqn string, visiting map[string]bool) bool {
+	if pure, ok := builtinPurity[strings.ToLower(fqn)]; ok {
+		return pure
+	}
+	if _, ok := constant.LookupFunc(fqn); ok {
+		return true
+	}
+	if _, ok := meta.Info.GetFunction(fqn); !ok {
+		// Undefined function.
+		return false
+	}
+	fqn = strings.ToLower(fqn)
+	if visiting[fqn] {
+		return false
+	}
+
+	m.mu.Lock()
+	pure, known := m.funcPurity[fqn]
+	summary := m.funcSummaries[fqn]
+	m.mu.Unlock()
+	if known {
+		return pure
+	}
+
+	pure = summary != nil && !summary.impure
+	if pure {
+		if visiting == nil {
+			visiting = map[string]bool{}
+		}
+		visiting[fqn] = true
+		for _, call := range summary.calls {
+			callee, ok := funcCallName(&summary.st, call)
+			if !ok || !m.checkPureFunc(callee, visiting) {
+				pure = false
+				break
+			}
+		}
+		delete(visiting, fqn)
+	}
+
+	m.mu.Lock()
+	m.funcPurity[fqn] = pure
+	m.mu.Unlock()
+	return pure
+}
+
+// effectsVisitor finds side effects inside a function body.
+//
+// Local variables modifications are not side effects,
+// but params can be objects, so their elements can't be modified.
+type effectsVisitor struct {
+	summary *funcSummary
+	params  map[string]bool
+}
+
+func (v *effectsVisitor) GetChildrenVisitor(key string) walker.Visitor { return v }
+func (v *effectsVisitor) LeaveNode(w walker.Walkable)                  {}
+
+func (v *effectsVisitor) EnterNode(w walker.Walkable) bool {
+	if v.summary.impure {
+		return false
+	}
+
+	switch n := w.(type) {
+	case *stmt.Echo, *stmt.InlineHtml, *stmt.Global, *stmt.Static,
+		*stmt.Function, *stmt.Class, *stmt.Interface, *stmt.Trait,
+		*expr.Print, *expr.Exit, *expr.Die, *expr.ShellExec,
+		*expr.Yield, *expr.YieldFrom, *expr.Clone,
+		*expr.Include, *expr.IncludeOnce, *expr.Require, *expr.RequireOnce, *expr.Eval,
+		*expr.MethodCall, *expr.StaticCall, *expr.New:
+		v.summary.impure = true
+		return false
+	case *expr.Closure:
+		// Closure body is executed only when it's called.
+		return false
+
+	case *expr.FunctionCall:
+		v.summary.calls = append(v.summary.calls, n)
+
+	case *assign.Reference:
+		v.checkWrite(n.Variable)
+		v.checkWrite(n.Expression)
+	case *assign.Assign:
+		v.checkWrite(n.Variable)
+	case *assign.Plus:
+		v.checkWrite(n.Variable)
+	case *assign.Minus:
+		v.checkWrite(n.Variable)
+	case *assign.Mul:
+		v.checkWrite(n.Variable)
+	case *assign.Div:
+		v.checkWrite(n.Variable)
+	case *assign.Mod:
+		v.checkWrite(n.Variable)
+	case *assign.Pow:
+		v.checkWrite(n.Variable)
+	case *assign.Concat:
+		v.checkWrite(n.Variable)
+	case *assign.BitwiseAnd:
+		v.checkWrite(n.Variable)
+	case *assign.BitwiseOr:
+		v.checkWrite(n.Variable)
+	case *assign.BitwiseXor:
+		v.checkWrite(n.Variable)
+	case *assign.ShiftLeft:
+		v.checkWrite(n.Variable)
+	case *assign.ShiftRight:
+		v.checkWrite(n.Variable)
+	case *expr.PreInc:
+		v.checkWrite(n.Variable)
+	case *expr.PostInc:
+		v.checkWrite(n.Variable)
+	case *expr.PreDec:
+		v.checkWrite(n.Variable)
+	case *expr.PostDec:
+		v.checkWrite(n.Variable)
+	case *stmt.Unset:
+		for _, x := range n.Vars {
+			v.checkWrite(x)
+		}
+	case *stmt.Foreach:
+		v.checkWrite(n.Key)
+		v.checkWrite(n.Variable)
+		if n.ByRef {
+			v.checkWrite(n.Expr)
+		}
+	}
+
+	return true
+}
+
+// checkWrite marks the function as impure unless n
+// write modifies only the local variables.
+func (v *effectsVisitor) checkWrite(n node.Node) {
+	if list := listItems(n); list != nil {
+		for _, item := range list {
+			if item, ok := item.(*expr.ArrayItem); ok {
+				v.checkWrite(item.Val)
+			}
+		}
+		return
+	}
+	if n == nil {
+		return
+	}
+
+	switch n := n.(type) {
+	case *expr.Variable:
+		name, ok := localVarName(n)
+		if !ok || !isTrackedVar(name) {
+			v.summary.impure = true
+		}
+	case *expr.ArrayDimFetch:
+		var x node.Node = n
+		for {
+			fetch, ok := x.(*expr.ArrayDimFetch)
+			if !ok {
+				break
+			}
+			x = fetch.Variable
+		}
+		base, ok := x.(*expr.Variable)
+		if !ok {
+			v.summary.impure = true
+			return
+		}
+		name, ok := localVarName(base)
+		if !ok || !isTrackedVar(name) || v.params[name] {
+			v.summary.impure = true
+		}
+	default:
+		// Property and static property writes.
+		v.summary.impure = true
+	}
+}
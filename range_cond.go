@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math"
+
+	"github.com/quasilyte/php-critic/internal/constant"
+	"github.com/quasilyte/php-critic/internal/interval"
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/node/expr"
+	"github.com/z7zmey/php-parser/node/expr/binary"
+)
+
+// rangeCond describes a condition as a set of numbers
+// the subject expression belongs to when the condition is true.
+//
+// "$x > 5 && $x != 10" is described as (5, 10) U (10, +inf] set of $x.
+type rangeCond struct {
+	subject node.Node
+	set     interval.Set
+}
+
+// condRange returns a range description of the cond.
+//
+// Only comparisons of the simple expressions with numeric constants
+// and their logical combinations are supported.
+func condRange(mi *metainfoExt, cond node.Node) (rangeCond, bool) {
+	switch cond := cond.(type) {
+	case *binary.Smaller:
+		return cmpRange(mi, cond.Left, cond.Right, interval.Less, interval.Greater)
+	case *binary.SmallerOrEqual:
+		return cmpRange(mi, cond.Left, cond.Right, interval.LessOrEqual, interval.GreaterOrEqual)
+	case *binary.Greater:
+		return cmpRange(mi, cond.Left, cond.Right, interval.Greater, interval.Less)
+	case *binary.GreaterOrEqual:
+		return cmpRange(mi, cond.Left, cond.Right, interval.GreaterOrEqual, interval.LessOrEqual)
+	case *binary.Equal:
+		return cmpRange(mi, cond.Left, cond.Right, interval.Point, interval.Point)
+	case *binary.NotEqual:
+		return cmpRange(mi, cond.Left, cond.Right, interval.NotPoint, interval.NotPoint)
+
+	case *expr.BooleanNot:
+		r, ok := condRange(mi, cond.Expr)
+		r.set = r.set.Complement()
+		return r, ok
+	case *binary.BooleanAnd:
+		return combineRanges(mi, cond.Left, cond.Right, interval.Set.Intersect)
+	case *binary.BooleanOr:
+		return combineRanges(mi, cond.Left, cond.Right, interval.Set.Union)
+
+	case *expr.FunctionCall:
+		return inArrayRange(mi, cond)
+	}
+
+	return rangeCond{}, false
+}
+
+// chainRanges appends the ranges of the "&&" (if isAnd is true)
+// or "||" chain operands to dst. Operands that can't be described
+// by a range are skipped.
+//
+// For "&&" chains, every returned range holds when cond is true.
+// For "||" chains, every returned range fails when cond is false.
+func chainRanges(mi *metainfoExt, dst []rangeCond, cond node.Node, isAnd bool) []rangeCond {
+	if r, ok := condRange(mi, cond); ok {
+		return append(dst, r)
+	}
+	switch cond := cond.(type) {
+	case *binary.BooleanAnd:
+		if isAnd {
+			dst = chainRanges(mi, dst, cond.Left, isAnd)
+			dst = chainRanges(mi, dst, cond.Right, isAnd)
+		}
+	case *binary.BooleanOr:
+		if !isAnd {
+			dst = chainRanges(mi, dst, cond.Left, isAnd)
+			dst = chainRanges(mi, dst, cond.Right, isAnd)
+		}
+	}
+	return dst
+}
+
+// cmpRange describes "x op y" comparison.
+// op describes a range for the constant on the right side,
+// flippedOp is used when the constant is on the left.
+func cmpRange(mi *metainfoExt, x, y node.Node, op, flippedOp func(float64) interval.Set) (rangeCond, bool) {
+	if v, ok := rangeNumber(constFold(mi, y)); ok && isRangeSubject(mi, x) {
+		return rangeCond{subject: x, set: op(v)}, true
+	}
+	if v, ok := rangeNumber(constFold(mi, x)); ok && isRangeSubject(mi, y) {
+		return rangeCond{subject: y, set: flippedOp(v)}, true
+	}
+	return rangeCond{}, false
+}
+
+func combineRanges(mi *metainfoExt, x, y node.Node, op func(a, b interval.Set) interval.Set) (rangeCond, bool) {
+	r1, ok := condRange(mi, x)
+	if !ok {
+		return rangeCond{}, false
+	}
+	r2, ok := condRange(mi, y)
+	if !ok || !sameExpr(mi, r1.subject, r2.subject) {
+		return rangeCond{}, false
+	}
+	return rangeCond{subject: r1.subject, set: op(r1.set, r2.set)}, true
+}
+
+// inArrayRange describes "in_array($x, [1, 2, 3])" call.
+func inArrayRange(mi *metainfoExt, call *expr.FunctionCall) (rangeCond, bool) {
+	fqn, ok := funcCallName(mi.classParseState(), call)
+	if !ok || fqn != `\in_array` || len(call.Arguments) < 2 {
+		return rangeCond{}, false
+	}
+	if len(call.Arguments) > 2 {
+		// Strict in_array() also compares the types,
+		// so "1" or 1.0 needle is not in [1] array.
+		strict, ok := constant.ToBool(constFold(mi, call.Arguments[2]))
+		if !ok || bool(strict) {
+			return rangeCond{}, false
+		}
+	}
+	subject := call.Arguments[0].(*node.Argument).Expr
+	if !isRangeSubject(mi, subject) {
+		return rangeCond{}, false
+	}
+	arr, ok := constFold(mi, call.Arguments[1]).(*constant.ArrayValue)
+	if !ok {
+		return rangeCond{}, false
+	}
+	set := interval.Empty()
+	numeric := true
+	arr.Iterate(func(_, elem constant.Value) {
+		v, ok := rangeNumber(elem)
+		if !ok {
+			numeric = false
+			return
+		}
+		set = set.Union(interval.Point(v))
+	})
+	if !numeric {
+		return rangeCond{}, false
+	}
+	return rangeCond{subject: subject, set: set}, true
+}
+
+// isRangeSubject reports whether e can be described by a range.
+// Such expressions have no side effects and their values are unknown.
+func isRangeSubject(mi *metainfoExt, e node.Node) bool {
+	if !isSideEffectFree(mi, e) {
+		return false
+	}
+	_, ok := constFold(mi, e).(constant.UnknownValue)
+	return ok
+}
+
+// rangeNumber converts a numeric constant to the range bound.
+// Ints that can't be represented as floats precisely are rejected.
+func rangeNumber(v constant.Value) (float64, bool) {
+	const maxExactInt = 1 << 53
+	switch v := v.(type) {
+	case constant.IntValue:
+		if v > maxExactInt || v < -maxExactInt {
+			return 0, false
+		}
+		return float64(v), true
+	case constant.FloatValue:
+		if math.IsNaN(float64(v)) {
+			return 0, false
+		}
+		return float64(v), true
+	}
+	return 0, false
+}
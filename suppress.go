@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/z7zmey/php-parser/node"
+	"github.com/z7zmey/php-parser/position"
+	"github.com/z7zmey/php-parser/scanner"
+	"github.com/z7zmey/php-parser/token"
+	"github.com/z7zmey/php-parser/walker"
+)
+
+// suppressionRE matches "php-critic:ignore" directives in a comment line.
+// The first group is a comment start, the second group holds the directive args.
+var suppressionRE = regexp.MustCompile(`^(//|#|/\*+|\s*\*)\s*php-critic:ignore\b(.*)$`)
+
+// suppression is a parsed inline suppression directive:
+//
+//	// php-critic:ignore badCond,dupArg -- reason
+//
+// Directive that follows the code on the same line suppresses the reports
+// of the statement that starts on that line (or only that line reports).
+// Directive on its own line suppresses the reports of the next statement
+// in the same block or the reports of the whole block if there is no next statement.
+// Directive inside a PHPDoc comment suppresses the reports of the documented function.
+type suppression struct {
+	// node is the node that is the closest to the directive.
+	// Directives are not the tree nodes, so they're reported at it.
+	// It's nil if there is no code on the directive line and after it.
+	node node.Node
+
+	checks []string
+	// used records the checks that suppressed something.
+	used map[string]bool
+
+	// err describes a malformed directive, which suppresses nothing.
+	err string
+
+	// from and to are the suppressed lines range.
+	from, to int
+}
+
+// suppressions are all suppression directives of a file.
+type suppressions []*suppression
+
+// suppress reports whether the checkName report at the line is suppressed.
+func (s suppressions) suppress(checkName string, line int) bool {
+	suppressed := false
+	for _, sup := range s {
+		if sup.err != "" || line < sup.from || line > sup.to {
+			continue
+		}
+		for _, c := range sup.checks {
+			if c == checkName {
+				sup.used[c] = true
+				suppressed = true
+			}
+		}
+	}
+	return suppressed
+}
+
+// parseSuppressions finds all suppression directives of the file.
+func parseSuppressions(positions position.Positions, lines [][]byte, root node.Node) suppressions {
+	f := &suppressionsFile{positions: positions, lines: lines}
+	root.Walk(&blocksCollector{positions: positions, blocks: &f.blocks})
+	pos := 0
+	for _, line := range lines {
+		f.linesPositions = append(f.linesPositions, pos)
+		pos += len(line) + len("\n")
+	}
+
+	var list suppressions
+	for _, c := range sourceComments(bytes.Join(lines, []byte("\n"))) {
+		// The comment lines after the first one start at the line beginning.
+		i := sort.SearchInts(f.linesPositions, c.offset+1) - 1
+		column := c.offset - f.linesPositions[i]
+		for _, text := range strings.Split(c.text, "\n") {
+			text = strings.TrimSuffix(text, "\r")
+			if m := suppressionRE.FindStringSubmatchIndex(text); m != nil {
+				list = append(list, f.newSuppression(i, column+m[2], text[m[4]:m[5]]))
+			}
+			i++
+			column = 0
+		}
+	}
+	return list
+}
+
+// suppressionsFile is a file that is searched for suppression directives.
+type suppressionsFile struct {
+	positions      position.Positions
+	lines          [][]byte
+	linesPositions []int
+	blocks         []*stmtBlock
+}
+
+// newSuppression creates a suppression from the directive args
+// that are found at the column of the i-th line.
+func (f *suppressionsFile) newSuppression(i, column int, args string) *suppression {
+	line := f.lines[i]
+	lineNum := i + 1
+	sup := &suppression{
+		node: f.closestNode(lineNum, f.linesPositions[i]+column+1),
+		used: map[string]bool{},
+	}
+	sup.checks, sup.err = parseSuppressionArgs(args)
+	trailing := len(bytes.TrimSpace(line[:column])) != 0
+	sup.from, sup.to = suppressionScope(f.positions, f.blocks, lineNum, trailing)
+	return sup
+}
+
+// closestNode returns the outermost node that starts first on the line.
+// If there is no such node, it returns the first node that starts after the pos
+// or, for the directives at the end of the file, the last node.
+func (f *suppressionsFile) closestNode(line, pos int) node.Node {
+	var closest, last node.Node
+	var closestPos, lastPos *position.Position
+	for n, p := range f.positions {
+		if lastPos == nil || p.StartPos > lastPos.StartPos {
+			last, lastPos = n, p
+		}
+		if p.StartLine != line && p.StartPos < pos {
+			continue
+		}
+		if closestPos != nil {
+			onLine := closestPos.StartLine == line
+			switch {
+			case onLine && p.StartLine != line:
+				continue
+			case onLine == (p.StartLine == line) && p.StartPos > closestPos.StartPos:
+				continue
+			case p.StartPos == closestPos.StartPos && p.EndPos <= closestPos.EndPos:
+				continue
+			}
+		}
+		closest, closestPos = n, p
+	}
+	if closest == nil {
+		return last
+	}
+	return closest
+}
+
+// sourceComment is a comment of the PHP source.
+type sourceComment struct {
+	offset int
+	text   string
+}
+
+// sourceComments returns all comments of the src.
+//
+// The source is tokenized by the parser lexer, so the comment-like
+// text inside the strings and heredocs is not a comment.
+// The lexer doesn't record the comment positions, but the comments
+// that precede a token are the only non-space text after the previous token.
+func sourceComments(src []byte) []sourceComment {
+	var list []sourceComment
+	lexer := scanner.NewLexer(bytes.NewReader(src), "")
+	var lval lastToken
+	offset := 0
+	for {
+		tok := lexer.Lex(&lval)
+		for _, c := range lexer.Comments {
+			text := c.String()
+			i := strings.Index(string(src[offset:]), text)
+			if i == -1 {
+				break
+			}
+			list = append(list, sourceComment{offset: offset + i, text: text})
+			offset += i + len(text)
+		}
+		if tok <= 0 {
+			return list
+		}
+		if lval.tok.EndPos > offset {
+			offset = lval.tok.EndPos
+		}
+	}
+}
+
+// lastToken records the last token of the lexer.
+type lastToken struct {
+	tok token.Token
+}
+
+func (l *lastToken) Token(tok token.Token) { l.tok = tok }
+
+// parseSuppressionArgs parses "checks -- reason" part of the directive.
+func parseSuppressionArgs(args string) (checks []string, err string) {
+	args = strings.TrimSpace(args)
+	args = strings.TrimSpace(strings.TrimSuffix(args, "*/"))
+	sep := strings.Index(args, "--")
+	if sep == -1 || strings.TrimSpace(args[sep+len("--"):]) == "" {
+		return nil, "missing reason, expected 'php-critic:ignore checks -- reason'"
+	}
+	for _, name := range strings.Split(args[:sep], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := checksByName[name]; !ok {
+			return nil, fmt.Sprintf("unknown check %q", name)
+		}
+		checks = append(checks, name)
+	}
+	if len(checks) == 0 {
+		return nil, "no checks to suppress"
+	}
+	return checks, ""
+}
+
+// suppressionScope returns the lines range that is suppressed
+// by the directive at the given line.
+func suppressionScope(positions position.Positions, blocks []*stmtBlock, line int, trailing bool) (from, to int) {
+	if trailing {
+		from, to = line, line
+		for _, b := range blocks {
+			for _, s := range b.stmts {
+				pos := positions[s]
+				if pos != nil && pos.StartLine == line && pos.EndLine > to {
+					to = pos.EndLine
+				}
+			}
+		}
+		return from, to
+	}
+
+	// Nested blocks are collected after their parents,
+	// so the last one wins among the blocks with the same lines range.
+	var inner *stmtBlock
+	for _, b := range blocks {
+		if b.from <= line && line <= b.to && (inner == nil || b.to-b.from <= inner.to-inner.from) {
+			inner = b
+		}
+	}
+	if inner == nil {
+		return line, line
+	}
+	for _, s := range inner.stmts {
+		pos := positions[s]
+		if pos != nil && pos.StartLine > line {
+			return pos.StartLine, pos.EndLine
+		}
+	}
+	return inner.from, inner.to
+}
+
+// stmtBlock is a statements list along with its owner lines range.
+type stmtBlock struct {
+	from, to int
+	stmts    []node.Node
+}
+
+// blocksCollector collects all statement lists of the walked tree.
+type blocksCollector struct {
+	positions position.Positions
+	blocks    *[]*stmtBlock
+
+	// block collects the visited nodes, if not nil.
+	block *stmtBlock
+	// cur is the last visited node.
+	cur node.Node
+}
+
+func (v *blocksCollector) EnterNode(w walker.Walkable) bool {
+	n, ok := w.(node.Node)
+	if !ok {
+		return false
+	}
+	v.cur = n
+	if v.block != nil {
+		v.block.stmts = append(v.block.stmts, n)
+	}
+	return true
+}
+
+func (v *blocksCollector) GetChildrenVisitor(key string) walker.Visitor {
+	child := &blocksCollector{positions: v.positions, blocks: v.blocks}
+	if key == "Stmts" {
+		// Blocks without positions, like the file root, cover everything.
+		b := &stmtBlock{from: 1, to: math.MaxInt32}
+		if pos := v.positions[v.cur]; pos != nil {
+			b.from, b.to = pos.StartLine, pos.EndLine
+		}
+		*v.blocks = append(*v.blocks, b)
+		child.block = b
+	}
+	return child
+}
+
+func (v *blocksCollector) LeaveNode(w walker.Walkable) {}
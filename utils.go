@@ -1,19 +1,24 @@
 package main
 
 import (
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"unicode"
-	"unicode/utf8"
 
 	"github.com/VKCOM/noverify/src/meta"
+	"github.com/VKCOM/noverify/src/solver"
+	"github.com/quasilyte/php-critic/internal/astcmp"
+	"github.com/quasilyte/php-critic/internal/astprint"
 	"github.com/quasilyte/php-critic/internal/constant"
 	"github.com/z7zmey/php-parser/node"
 	"github.com/z7zmey/php-parser/node/expr"
 	"github.com/z7zmey/php-parser/node/expr/binary"
+	"github.com/z7zmey/php-parser/node/expr/cast"
 	"github.com/z7zmey/php-parser/node/name"
 	"github.com/z7zmey/php-parser/node/scalar"
+	"github.com/z7zmey/php-parser/walker"
 )
 
 // FIXME: is *scalar.String actually ever contain unescaped $ signs?
@@ -35,40 +40,174 @@ func constFold(mi *metainfoExt, e node.Node) constant.Value {
 		return constant.Add(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *binary.Minus:
 		return constant.Sub(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.Mul:
+		return constant.Mul(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.Div:
+		return constant.Div(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.Mod:
+		return constant.Mod(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.Pow:
+		return constant.Pow(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *expr.UnaryMinus:
 		return constant.Neg(constFold(mi, e.Expr))
+	case *expr.UnaryPlus:
+		return constant.Plus(constFold(mi, e.Expr))
+
+	case *binary.BitwiseAnd:
+		return constant.BitAnd(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.BitwiseOr:
+		return constant.BitOr(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.BitwiseXor:
+		return constant.BitXor(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.ShiftLeft:
+		return constant.ShiftLeft(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.ShiftRight:
+		return constant.ShiftRight(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *expr.BitwiseNot:
+		return constant.BitNot(constFold(mi, e.Expr))
 
 	case *binary.Smaller:
 		return constant.LessThan(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *binary.Greater:
 		return constant.GreaterThan(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.SmallerOrEqual:
+		return constant.LessOrEqual(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.GreaterOrEqual:
+		return constant.GreaterOrEqual(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.Spaceship:
+		return constant.Spaceship(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *expr.BooleanNot:
 		return constant.Not(constFold(mi, e.Expr))
 	case *binary.BooleanAnd:
 		return constant.And(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *binary.BooleanOr:
 		return constant.Or(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.LogicalAnd:
+		return constant.And(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.LogicalOr:
+		return constant.Or(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.LogicalXor:
+		return constant.Xor(constFold(mi, e.Left), constFold(mi, e.Right))
 
 	case *binary.Equal:
 		return constant.Equal(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.NotEqual:
+		return constant.NotEqual(constFold(mi, e.Left), constFold(mi, e.Right))
 	case *binary.Identical:
 		return constant.Identical(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *binary.NotIdentical:
+		return constant.NotIdentical(constFold(mi, e.Left), constFold(mi, e.Right))
+
+	case *binary.Coalesce:
+		switch constFoldIsset(mi, e.Left) {
+		case constant.BoolValue(true):
+			return constFold(mi, e.Left)
+		case constant.BoolValue(false):
+			return constFold(mi, e.Right)
+		}
+		return constant.Coalesce(constFold(mi, e.Left), constFold(mi, e.Right))
+	case *expr.Ternary:
+		cond := constFold(mi, e.Condition)
+		v, ok := constant.ToBool(cond)
+		if !ok {
+			return constant.UnknownValue{}
+		}
+		switch {
+		case bool(v) && e.IfTrue == nil:
+			// Short "?:" form.
+			return cond
+		case bool(v):
+			return constFold(mi, e.IfTrue)
+		default:
+			return constFold(mi, e.IfFalse)
+		}
+
+	case *expr.Variable:
+		return constFoldVariable(mi, e)
 
 	case *expr.ConstFetch:
-		name := nodeToNameString(mi.st, e.Constant)
-		return constFold(mi, mi.constValue[name])
+		switch strings.ToLower(strings.TrimPrefix(meta.NameNodeToString(e.Constant), `\`)) {
+		case "null":
+			return constant.NullValue{}
+		case "true":
+			return constant.BoolValue(true)
+		case "false":
+			return constant.BoolValue(false)
+		}
+		return constFoldConstFetch(mi, e)
+	case *scalar.MagicConstant:
+		return constFoldMagicConstant(mi, e)
+
+	case *expr.ClassConstFetch:
+		return constFoldClassConst(mi, e)
 
 	case *expr.FunctionCall:
-		switch meta.NameNodeToString(e.Function) {
-		case "strlen":
-			if len(e.Arguments) != 1 {
+		fqn, ok := funcCallName(mi.classParseState(), e)
+		if !ok {
+			return constant.UnknownValue{}
+		}
+		if _, ok := constant.LookupFunc(fqn); !ok {
+			return constant.UnknownValue{}
+		}
+		args := make([]constant.Value, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			arg, ok := arg.(*node.Argument)
+			if !ok || arg.Variadic || arg.IsReference {
 				return constant.UnknownValue{}
 			}
-			s, ok := constFold(mi, e.Arguments[0]).(constant.StringValue)
-			if !ok {
-				return constant.UnknownValue{}
+			args[i] = constFold(mi, arg.Expr)
+		}
+		return constant.Call(fqn, args)
+
+	case *expr.Isset:
+		for _, v := range e.Variables {
+			isset := constFoldIsset(mi, v)
+			if isset != constant.BoolValue(true) {
+				return isset
 			}
-			return constant.IntValue(len(s))
+		}
+		return constant.BoolValue(true)
+
+	case *expr.ArrayDimFetch:
+		if e.Dim == nil {
+			return constant.UnknownValue{}
+		}
+		arr, ok := constFold(mi, e.Variable).(*constant.ArrayValue)
+		if !ok {
+			return constant.UnknownValue{}
+		}
+		if v, ok := arr.Get(constFold(mi, e.Dim)); ok {
+			return v
+		}
+	case *expr.Array:
+		return constFoldArray(mi, e.Items)
+	case *expr.ShortArray:
+		return constFoldArray(mi, e.Items)
+
+	case *cast.Int:
+		if v, ok := constant.ToInt(constFold(mi, e.Expr)); ok {
+			return v
+		}
+	case *cast.Double:
+		if v, ok := constant.ToFloat(constFold(mi, e.Expr)); ok {
+			return v
+		}
+	case *cast.String:
+		if v, ok := constant.ToString(constFold(mi, e.Expr)); ok {
+			return v
+		}
+	case *cast.Bool:
+		if v, ok := constant.ToBool(constFold(mi, e.Expr)); ok {
+			return v
+		}
+	case *cast.Array:
+		if v, ok := constant.ToArray(constFold(mi, e.Expr)); ok {
+			return v
+		}
+	case *cast.Unset:
+		// (unset) cast is removed in PHP 8.
+		if constant.TargetVersion < constant.PHP8 {
+			return constant.NullValue{}
 		}
 
 	case *binary.Concat:
@@ -77,30 +216,264 @@ func constFold(mi *metainfoExt, e node.Node) constant.Value {
 		if isDynamicString(e) {
 			return constant.UnknownValue{}
 		}
-		unquoted := e.Value[1 : len(e.Value)-1]
-		s, ok := interpretString(unquoted, e.Value[0])
+		lit := strings.TrimLeft(e.Value, "bB") // Binary string prefix
+		s, ok := interpretString(lit[1:len(lit)-1], lit[0])
+		if !ok {
+			return constant.UnknownValue{}
+		}
+		return constant.StringValue(s)
+	case *scalar.Heredoc:
+		s, ok := interpretHeredoc(e)
 		if !ok {
 			return constant.UnknownValue{}
 		}
 		return constant.StringValue(s)
 	case *scalar.Dnumber:
-		v, err := strconv.ParseFloat(e.Value, 64)
-		if err == nil {
-			return constant.FloatValue(v)
+		// Also used for the int literals that overflow int.
+		if v, ok := constant.ParseNumber(e.Value); ok {
+			return v
 		}
 	case *scalar.Lnumber:
-		v, err := strconv.ParseInt(e.Value, 10, 64)
-		if err == nil {
-			return constant.IntValue(v)
+		if v, ok := constant.ParseNumber(e.Value); ok {
+			return v
+		}
+	}
+
+	return constant.UnknownValue{}
+}
+
+// constFoldIsset evaluates isset() for a single variable v.
+//
+// Only the array elements of the known arrays are handled, so it
+// can tell that the key is missing or the element is null.
+func constFoldIsset(mi *metainfoExt, v node.Node) constant.Value {
+	fetch, ok := v.(*expr.ArrayDimFetch)
+	if !ok || fetch.Dim == nil {
+		return constant.UnknownValue{}
+	}
+	arr, ok := constFold(mi, fetch.Variable).(*constant.ArrayValue)
+	if !ok {
+		return constant.UnknownValue{}
+	}
+	key := constFold(mi, fetch.Dim)
+	if _, ok := constant.NormalizeKey(key); !ok {
+		return constant.UnknownValue{}
+	}
+	elem, ok := arr.Get(key)
+	if !ok {
+		return constant.BoolValue(false)
+	}
+	if _, ok := elem.(constant.NullValue); ok {
+		return constant.BoolValue(false)
+	}
+	return constant.BoolValue(true)
+}
+
+// constFoldConstFetch evaluates global constant fetch expression.
+//
+// Constants from the current namespace take precedence over
+// the predefined constants, but the latter can't be redefined globally.
+func constFoldConstFetch(mi *metainfoExt, e *expr.ConstFetch) constant.Value {
+	st := mi.st
+	if mi.constState != nil {
+		st = mi.constState
+	}
+	constName := nodeToNameString(st, e.Constant)
+	if c, ok := mi.constValue[constName]; ok && st.Namespace != "" {
+		return constFold(mi, c)
+	}
+	globalName := strings.TrimPrefix(meta.NameNodeToString(e.Constant), `\`)
+	if !strings.Contains(globalName, `\`) {
+		if v, ok := constant.Predefined(globalName); ok {
+			return v
+		}
+	}
+	return constFold(mi, mi.constValue[constName])
+}
+
+// constFoldMagicConstant evaluates magic constants like __CLASS__ and __LINE__.
+func constFoldMagicConstant(mi *metainfoExt, e *scalar.MagicConstant) constant.Value {
+	st := mi.classParseState()
+	kind := strings.ToUpper(e.Value)
+	switch kind {
+	case "__CLASS__":
+		if st.IsTrait {
+			// Resolved to the class that uses the trait.
+			return constant.UnknownValue{}
 		}
+		return constant.StringValue(strings.TrimPrefix(st.CurrentClass, `\`))
+	case "__TRAIT__":
+		if !st.IsTrait {
+			return constant.StringValue("")
+		}
+		return constant.StringValue(strings.TrimPrefix(st.CurrentClass, `\`))
+	case "__NAMESPACE__":
+		return constant.StringValue(strings.TrimPrefix(st.Namespace, `\`))
 	}
 
+	// Other constants depend on the expression location.
+	// Class constant initializers can come from the other files.
+	if mi.constState != nil {
+		return constant.UnknownValue{}
+	}
+	info, ok := mi.ctxt.RootState()[fileInfoKey].(*fileInfo)
+	if !ok {
+		return constant.UnknownValue{}
+	}
+	switch kind {
+	case "__LINE__":
+		pos, ok := info.nodePositions()[e]
+		if !ok {
+			return constant.UnknownValue{}
+		}
+		return constant.IntValue(pos.StartLine)
+	case "__FILE__":
+		return constant.StringValue(info.filename)
+	case "__DIR__":
+		return constant.StringValue(filepath.Dir(info.filename))
+	case "__FUNCTION__", "__METHOD__":
+		switch {
+		case info.propagating:
+			// Current context can belong to a nested closure.
+			return constant.UnknownValue{}
+		case mi.ctxt.IsRootLevel():
+			return constant.StringValue("")
+		case mi.ctxt.Scope().IsInClosure():
+			return constant.UnknownValue{}
+		case kind == "__FUNCTION__":
+			return constant.StringValue(info.function)
+		default:
+			return constant.StringValue(info.method)
+		}
+	}
+	return constant.UnknownValue{}
+}
+
+// constFoldVariable returns a value of the local variable
+// that is known at the e read location.
+//
+// Local variables values are computed once per function,
+// when they're requested for the first time.
+func constFoldVariable(mi *metainfoExt, e *expr.Variable) constant.Value {
+	if mi.constState != nil || mi.ctxt.IsRootLevel() {
+		return constant.UnknownValue{}
+	}
+	info, ok := mi.ctxt.RootState()[fileInfoKey].(*fileInfo)
+	if !ok || info.fn == nil {
+		return constant.UnknownValue{}
+	}
+	if info.locals == nil {
+		info.locals = localConsts{}
+		info.propagating = true
+		propagateConsts(mi, info.fn, info.locals)
+		info.propagating = false
+	}
+	if v, ok := info.locals[e]; ok {
+		return v
+	}
 	return constant.UnknownValue{}
 }
 
+// constFoldClassConst evaluates class constant fetch expression.
+//
+// Constants are resolved through the class hierarchy and their
+// initializers are folded in the context of the declaring class.
+func constFoldClassConst(mi *metainfoExt, e *expr.ClassConstFetch) constant.Value {
+	if id, ok := e.Class.(*node.Identifier); ok && id.Value == "static" {
+		// Late static binding can refer to any derived class.
+		return constant.UnknownValue{}
+	}
+	className, ok := solver.GetClassName(mi.classParseState(), e.Class)
+	if !ok || className == "" {
+		return constant.UnknownValue{}
+	}
+	constName := e.ConstantName.(*node.Identifier).Value
+	if strings.EqualFold(constName, "class") {
+		return constant.StringValue(strings.TrimPrefix(className, `\`))
+	}
+
+	_, implClassName, ok := solver.FindConstant(className, constName)
+	if !ok {
+		return constant.UnknownValue{}
+	}
+	init, ok := mi.getClassConst(implClassName, constName)
+	if !ok || mi.classConstFolding.contains(init) {
+		return constant.UnknownValue{}
+	}
+
+	// The folding state is per call: the initializer is folded
+	// by the mi copy, so mi itself is never modified.
+	inner := *mi
+	inner.constState = &init.st
+	inner.classConstFolding = &constFolding{init: init, outer: mi.classConstFolding}
+	return constFold(&inner, init.expr)
+}
+
+// funcCallName returns a fully-qualified name of the called function.
+//
+// Unqualified names are resolved to the current namespace function if
+// it's defined and to the global function otherwise.
+func funcCallName(st *meta.ClassParseState, call *expr.FunctionCall) (string, bool) {
+	switch nm := call.Function.(type) {
+	case *name.FullyQualified:
+		return meta.FullyQualifiedToString(nm), true
+	case *name.Name:
+		nameStr := meta.NameToString(nm)
+		firstPart := nm.Parts[0].(*name.NamePart).Value
+		if alias, ok := st.FunctionUses[firstPart]; ok {
+			if len(nm.Parts) == 1 {
+				return alias, true
+			}
+			return alias + `\` + meta.NamePartsToString(nm.Parts[1:]), true
+		}
+		if len(nm.Parts) != 1 {
+			return st.Namespace + `\` + nameStr, true
+		}
+		if st.Namespace != "" {
+			local := st.Namespace + `\` + nameStr
+			if _, ok := meta.Info.GetFunction(local); ok {
+				return local, true
+			}
+		}
+		return `\` + nameStr, true
+	}
+	return "", false
+}
+
+func constFoldArray(mi *metainfoExt, items []node.Node) constant.Value {
+	arr := constant.NewArray()
+	for _, item := range items {
+		if item == nil {
+			continue // Trailing comma
+		}
+		item, ok := item.(*expr.ArrayItem)
+		if !ok || item.ByRef || item.Val == nil {
+			return constant.UnknownValue{}
+		}
+		v := constFold(mi, item.Val)
+		if _, ok := v.(constant.UnknownValue); ok {
+			return v
+		}
+		if item.Key == nil {
+			arr.Append(v)
+			continue
+		}
+		if !arr.Set(constFold(mi, item.Key), v) {
+			return constant.UnknownValue{}
+		}
+	}
+	return arr
+}
+
+// heredocQuote is a pseudo quote that makes interpretString use heredoc rules.
+// Heredoc follows the double-quoted strings escaping, but \" is not an escape there.
+const heredocQuote = '<'
+
+// interpretString returns a string value of the literal s contents.
+// quote is the literal opening quote: ', " or heredocQuote.
 func interpretString(s string, quote byte) (string, bool) {
 	switch quote {
-	case '\'', '"':
+	case '\'', '"', heredocQuote:
 		// OK
 	default:
 		return "", false
@@ -111,91 +484,140 @@ func interpretString(s string, quote byte) (string, bool) {
 		return s, true
 	}
 
+	if quote == '\'' {
+		// Only \\ and \' are escapes inside single-quoted strings.
+		var out strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '\'') {
+				i++
+			}
+			out.WriteByte(s[i])
+		}
+		return out.String(), true
+	}
+
 	var out strings.Builder
-	i := 0
-	for i < len(s) {
+	for i := 0; i < len(s); i++ {
 		ch := s[i]
-		switch {
-		case ch == '\\':
-			if len(s) < i+1 {
+		if ch != '\\' || i+1 == len(s) {
+			out.WriteByte(ch)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'v':
+			out.WriteByte('\v')
+		case 'e':
+			out.WriteByte(0x1b)
+		case 'f':
+			out.WriteByte('\f')
+		case '\\', '$':
+			out.WriteByte(s[i])
+		case '"':
+			if quote == heredocQuote {
+				out.WriteByte('\\')
+			}
+			out.WriteByte('"')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// Up to 3 octal digits; overflowing values are truncated to a byte.
+			end := i + 1
+			for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			v, _ := strconv.ParseUint(s[i:end], 8, 16)
+			out.WriteByte(byte(v))
+			i = end - 1
+		case 'x':
+			end := i + 1
+			for end < len(s) && end < i+3 && isHexDigit(s[end]) {
+				end++
+			}
+			if end == i+1 {
+				out.WriteString(`\x`)
+				break
+			}
+			v, _ := strconv.ParseUint(s[i+1:end], 16, 8)
+			out.WriteByte(byte(v))
+			i = end - 1
+		case 'u':
+			if i+1 == len(s) || s[i+1] != '{' {
+				out.WriteString(`\u`)
+				break
+			}
+			end := strings.IndexByte(s[i:], '}')
+			if end == -1 {
 				return "", false
 			}
-			switch s[i+1] {
-			case '\'':
-				if quote == '"' {
-					out.WriteString(`\'`)
-				} else {
-					out.WriteByte('\'')
-				}
-				i += 2
-			case '"':
-				if quote == '"' {
-					out.WriteByte('"')
-				} else {
-					out.WriteString(`\"`)
-				}
-				i += 2
-			case '$':
-				if quote == '"' {
-					out.WriteByte('$')
-				} else {
-					out.WriteString(`\$`)
-				}
-				i += 2
-			case 'n':
-				if quote == '"' {
-					out.WriteByte('\n')
-				} else {
-					out.WriteString(`\n`)
-				}
-				i += 2
-			case 'r':
-				if quote == '"' {
-					out.WriteByte('\r')
-				} else {
-					out.WriteString(`\r`)
-				}
-				i += 2
-			case 't':
-				if quote == '"' {
-					out.WriteByte('\r')
-				} else {
-					out.WriteString(`\r`)
-				}
-				i += 2
-			case '\\':
-				out.WriteByte(s[i+1])
-				i += 2
-			case 'x':
-				if quote == '"' {
-					if len(s) < i+3 {
-						return "", false
-					}
-					v, err := strconv.ParseInt(s[i+2:i+4], 16, 64)
-					if err != nil || v > 255 {
-						return "", false
-					}
-					out.WriteByte(byte(v))
-					i += 4
-				} else {
-					out.WriteString(`\x`)
-					i += 2
-				}
-			default:
+			end += i
+			digits := s[i+2 : end]
+			v, err := strconv.ParseUint(digits, 16, 32)
+			if digits == "" || digits[0] == '+' || err != nil || v > unicode.MaxRune {
+				// A compile error in PHP.
 				return "", false
 			}
-		case ch <= unicode.MaxASCII:
-			out.WriteByte(ch)
-			i++
+			writeUTF8(&out, rune(v))
+			i = end
 		default:
-			r, n := utf8.DecodeRuneInString(s[i:])
-			out.WriteRune(r)
-			i += n
+			// Unknown escapes are kept as is.
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
 		}
 	}
 	return out.String(), true
 }
 
+// interpretHeredoc returns a string value of the heredoc or nowdoc literal.
+func interpretHeredoc(lit *scalar.Heredoc) (string, bool) {
+	var raw strings.Builder
+	for _, p := range lit.Parts {
+		p, ok := p.(*scalar.EncapsedStringPart)
+		if !ok {
+			return "", false // Has interpolated parts
+		}
+		raw.WriteString(p.Value)
+	}
+	// The newline before the closing identifier is not a part of the string.
+	s := raw.String()
+	s = strings.TrimSuffix(s, "\n")
+	s = strings.TrimSuffix(s, "\r")
+
+	if strings.HasPrefix(lit.Label, "'") {
+		return s, true // Nowdoc
+	}
+	return interpretString(s, heredocQuote)
+}
+
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// writeUTF8 writes r UTF-8 encoding to the out.
+// Unlike utf8.EncodeRune, it encodes surrogate halves like PHP does.
+func writeUTF8(out *strings.Builder, r rune) {
+	switch {
+	case r < 0x80:
+		out.WriteByte(byte(r))
+	case r < 0x800:
+		out.WriteByte(byte(0xC0 | r>>6))
+		out.WriteByte(byte(0x80 | r&0x3F))
+	case r < 0x10000:
+		out.WriteByte(byte(0xE0 | r>>12))
+		out.WriteByte(byte(0x80 | (r>>6)&0x3F))
+		out.WriteByte(byte(0x80 | r&0x3F))
+	default:
+		out.WriteByte(byte(0xF0 | r>>18))
+		out.WriteByte(byte(0x80 | (r>>12)&0x3F))
+		out.WriteByte(byte(0x80 | (r>>6)&0x3F))
+		out.WriteByte(byte(0x80 | r&0x3F))
+	}
+}
+
 func nodeToNameString(st *meta.ClassParseState, n node.Node) string {
 	switch n := n.(type) {
 	case *node.Identifier:
@@ -211,40 +633,103 @@ func nodeToNameString(st *meta.ClassParseState, n node.Node) string {
 	}
 }
 
+// exprComparer is used to find duplicated expressions.
+// Operands order is not important for them.
+var exprComparer = astcmp.Config{Commutative: true}
+
+// codePrinter is used to quote the code in the report messages.
+// Long code is truncated to keep the messages readable.
+var codePrinter = astprint.Config{MaxLen: 60}
+
+// sameNode reports whether a and b are structurally equal.
 func sameNode(a, b node.Node) bool {
-	return sameSimpleExpr(a, b) || reflect.DeepEqual(a, b)
+	return astcmp.Equal(a, b)
 }
 
-func sameSimpleExpr(a, b node.Node) bool {
-	// TODO(quasilyte): handle const exprs?
-	switch a := a.(type) {
-	case *node.Argument:
-		b, ok := b.(*node.Argument)
-		return ok && sameSimpleExpr(a.Expr, b.Expr)
-	case *expr.ArrayDimFetch:
-		b, ok := b.(*expr.ArrayDimFetch)
-		return ok &&
-			sameSimpleExpr(a.Variable, b.Variable) &&
-			sameSimpleExpr(a.Dim, b.Dim)
-	case *expr.Variable:
-		b, ok := b.(*expr.Variable)
-		return ok && sameSimpleExpr(a.VarName, b.VarName)
-	case *node.Identifier:
-		b, ok := b.(*node.Identifier)
-		return ok && a.Value == b.Value
-	case *scalar.Lnumber:
-		b, ok := b.(*scalar.Lnumber)
-		return ok && a.Value == b.Value
-	case *binary.NotEqual:
-		b, ok := b.(*binary.NotEqual)
-		return ok &&
-			sameSimpleExpr(a.Left, b.Left) &&
-			sameSimpleExpr(a.Right, b.Right)
+// sameExpr reports whether a and b are equal side effect free expressions.
+// Expressions with side effects can give different results when evaluated twice.
+func sameExpr(mi *metainfoExt, a, b node.Node) bool {
+	return isSideEffectFree(mi, a) && exprComparer.Equal(a, b)
+}
+
+// isSideEffectFree reports whether n evaluation has no observable effects.
+//
+// Only pure function calls are permitted. Property fetches are
+// considered to be side effect free, even though they can invoke __get.
+func isSideEffectFree(mi *metainfoExt, n node.Node) bool {
+	v := sideEffectsVisitor{mi: mi}
+	n.Walk(&v)
+	return !v.found
+}
+
+type sideEffectsVisitor struct {
+	mi    *metainfoExt
+	found bool
+}
+
+func (v *sideEffectsVisitor) GetChildrenVisitor(key string) walker.Visitor { return v }
+func (v *sideEffectsVisitor) LeaveNode(w walker.Walkable)                  {}
+
+func (v *sideEffectsVisitor) EnterNode(w walker.Walkable) bool {
+	if v.found {
+		return false
+	}
+	switch n := w.(type) {
+	case *expr.FunctionCall:
+		fqn, ok := funcCallName(v.mi.classParseState(), n)
+		if ok && v.mi.isPureFunc(fqn) {
+			return true
+		}
+	case *node.Argument, *node.Identifier,
+		*name.Name, *name.FullyQualified, *name.Relative, *name.NamePart,
+		*expr.Variable, *expr.ArrayDimFetch, *expr.PropertyFetch, *expr.StaticPropertyFetch,
+		*expr.ConstFetch, *expr.ClassConstFetch,
+		*expr.Array, *expr.ShortArray, *expr.ArrayItem,
+		*expr.BooleanNot, *expr.BitwiseNot, *expr.UnaryMinus, *expr.UnaryPlus,
+		*expr.Ternary, *expr.Isset, *expr.Empty, *expr.InstanceOf,
+		*scalar.Lnumber, *scalar.Dnumber, *scalar.String, *scalar.MagicConstant,
+		*scalar.Encapsed, *scalar.EncapsedStringPart, *scalar.Heredoc:
+		return true
+	}
+	switch w.(type) {
+	case *cast.Int, *cast.Double, *cast.String, *cast.Bool, *cast.Array, *cast.Object, *cast.Unset:
+		return true
 	}
-	// TODO: handle constants?
+	if isBinaryOp(w) {
+		return true
+	}
+	v.found = true
 	return false
 }
 
+// isBinaryOp reports whether n is a binary operator expression, like "$x + $y".
+func isBinaryOp(n walker.Walkable) bool {
+	return reflect.TypeOf(n).Elem().PkgPath() == reflect.TypeOf(binary.Plus{}).PkgPath()
+}
+
+// nodeLine returns n start line or 0 if it's unknown.
+func nodeLine(mi *metainfoExt, n node.Node) int {
+	info, ok := mi.ctxt.RootState()[fileInfoKey].(*fileInfo)
+	if !ok {
+		return 0
+	}
+	pos, ok := info.nodePositions()[n]
+	if !ok {
+		return 0
+	}
+	return pos.StartLine
+}
+
+// plainArg returns the call argument expression if it's
+// neither variadic nor passed by reference.
+func plainArg(arg node.Node) (node.Node, bool) {
+	a, ok := arg.(*node.Argument)
+	if !ok || a.Variadic || a.IsReference {
+		return nil, false
+	}
+	return a.Expr, true
+}
+
 func intMax(x, y int) int {
 	if x > y {
 		return x
@@ -23,6 +23,11 @@ func TestInterpretString(t *testing.T) {
 		{`\\`, `\`},
 		{`\\\\`, `\\`},
 		{`\\x\\x`, `\x\x`},
+		{`\`, `\`},
+		{`a\`, `a\`},
+		{`\q`, `\q`},
+		{`\{`, `\{`},
+		{`é\\`, `é\`},
 	}
 
 	// Strings enclosed between ''.
@@ -35,18 +40,57 @@ func TestInterpretString(t *testing.T) {
 		{`\x00`, `\x00`},
 		{`\xff`, `\xff`},
 		{`\x1aaa`, `\x1aaa`},
+		{`\t\v\e\f`, `\t\v\e\f`},
+		{`\0\101`, `\0\101`},
+		{`\u{41}`, `\u{41}`},
+		{`\u{}`, `\u{}`},
 	}
 
 	// Strings enclosed between "".
 	q2tests := []simpleTestCase{
 		{`\$x`, `$x`},
 		{`\'`, `\'`},
-		{`\"`, `"`},
 		{`\n`, "\n"},
 		{`\r\n`, "\r\n"},
 		{`\x00`, "\x00"},
 		{`\xff`, "\xff"},
 		{`\x1aaa`, "\x1aaa"},
+		{`\xA`, "\n"},
+		{`\xg`, `\xg`},
+		{`\x`, `\x`},
+		{`\t`, "\t"},
+		{`\v`, "\v"},
+		{`\e`, "\x1b"},
+		{`\f`, "\f"},
+		{`\0`, "\x00"},
+		{`\08`, "\x008"},
+		{`\101\60`, "A0"},
+		{`\1234`, "S4"},
+		{`\400`, "\x00"},
+		{`\777`, "\xff"},
+		{`\u{41}`, "A"},
+		{`\u{00e9}`, "é"},
+		{`\u{1F600}`, "\U0001F600"},
+		{`\u{D800}`, "\xed\xa0\x80"},
+		{`\u41`, `\u41`},
+		{`\u`, `\u`},
+		{`\q\w`, `\q\w`},
+		{`\\n`, `\n`},
+	}
+
+	// Strings enclosed between "" only.
+	q2onlyTests := []simpleTestCase{
+		{`\"`, `"`},
+	}
+
+	// Heredoc strings.
+	heredocTests := []simpleTestCase{
+		{`\"`, `\"`},
+		{`"`, `"`},
+		{`\'`, `\'`},
+		{`\$x`, `$x`},
+		{`\t\n`, "\t\n"},
+		{`\u{41}\101`, "AA"},
 	}
 
 	var tests []testCase
@@ -59,8 +103,16 @@ func TestInterpretString(t *testing.T) {
 		tests = append(tests, testCase{quote: '\'', raw: test.raw, want: test.want})
 	}
 	for _, test := range q2tests {
+		tests = append(tests,
+			testCase{quote: '"', raw: test.raw, want: test.want},
+			testCase{quote: heredocQuote, raw: test.raw, want: test.want})
+	}
+	for _, test := range q2onlyTests {
 		tests = append(tests, testCase{quote: '"', raw: test.raw, want: test.want})
 	}
+	for _, test := range heredocTests {
+		tests = append(tests, testCase{quote: heredocQuote, raw: test.raw, want: test.want})
+	}
 
 	for _, test := range tests {
 		want := test.want
@@ -76,3 +128,21 @@ func TestInterpretString(t *testing.T) {
 		}
 	}
 }
+
+func TestInterpretStringError(t *testing.T) {
+	tests := []string{
+		`\u{}`,
+		`\u{41`,
+		`\u{xyz}`,
+		`\u{+41}`,
+		`\u{110000}`,
+	}
+
+	for _, raw := range tests {
+		for _, quote := range []byte{'"', heredocQuote} {
+			if have, ok := interpretString(raw, quote); ok {
+				t.Errorf("interpretString(%q, %v): expected an error, got %q", raw, quote, have)
+			}
+		}
+	}
+}